@@ -0,0 +1,130 @@
+package ptb
+
+import (
+	"fmt"
+)
+
+// storage key for player records
+const storage_RECORDS = "records"
+
+// Record keeps statistics for a player across games.
+type Record struct {
+	Rounds       int      // Number of games played.
+	Explosions   int      // Number of times the bomb exploded in this player's face.
+	Defuses      int      // Number of times the player defused the bomb.
+	Drops        int      // Number of times the player dropped the bomb.
+	Throws       int      // Number of times the player threw the bomb.
+	Rating       float64  // Skill rating.
+	Wins         int      // Number of games won.
+	Streak       int      // Number of consecutive games won.
+	Achievements []string // Names of unlocked achievements.
+}
+
+// unlocked returns true if the achievement with given name was unlocked.
+func (r *Record) unlocked(name string) bool {
+	for _, a := range r.Achievements {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Achievement is a badge players unlock by meeting a condition.
+// Conditions are checked at the end of every game.
+type Achievement struct {
+	Name        string                          // Name shown to players.
+	Description string                          // Explanation of the condition.
+	Check       func(p *Player, r *Record) bool // True if the player unlocked this achievement.
+}
+
+// DefaultAchievements returns the achievements used by a new game.
+func DefaultAchievements() []Achievement {
+	return []Achievement{
+		{"Hot Hands", "10 throws in one round", func(p *Player, r *Record) bool {
+			return p.Throws >= 10
+		}},
+		{"Bomb Whisperer", "defused 3 times", func(p *Player, r *Record) bool {
+			return r.Defuses >= 3
+		}},
+		{"Survivor", "never exploded in 20 rounds", func(p *Player, r *Record) bool {
+			return r.Rounds >= 20 && r.Explosions == 0
+		}},
+		{"Butterfingers", "dropped the bomb 5 times", func(p *Player, r *Record) bool {
+			return r.Drops >= 5
+		}},
+	}
+}
+
+// records returns player records, loading them from storage if needed.
+func (g *Game) records() map[string]*Record {
+
+	if g.Records == nil {
+		g.Records = make(map[string]*Record)
+		g.load(storage_RECORDS, &g.Records)
+	}
+
+	return g.Records
+}
+
+// record returns the record for given player, creating it if needed.
+func (g *Game) record(p *Player) *Record {
+
+	r, known := g.records()[p.ID]
+	if !known {
+		r = new(Record)
+		g.records()[p.ID] = r
+	}
+
+	if r.Rating == 0 {
+		r.Rating = tweak_RATING
+	}
+
+	return r
+}
+
+// updateRecords adds the results of this game to the player records,
+// updates ratings and announces newly unlocked achievements.
+func (g *Game) updateRecords() {
+
+	g.updateRatings()
+
+	for _, p := range g.Players {
+
+		r := g.record(p)
+
+		r.Rounds++
+		r.Throws = r.Throws + p.Throws
+		r.Drops = r.Drops + p.Drops
+
+		if p.Defused {
+			r.Defuses++
+		}
+
+		if p == g.Scores[0].Player {
+			r.Wins++
+			r.Streak++
+		} else {
+			r.Streak = 0
+		}
+
+		if p.Dead && !g.bomb.fake {
+			r.Explosions++
+		}
+
+		for _, a := range g.Config.Achievements {
+			if r.unlocked(a.Name) || !a.Check(p, r) {
+				continue
+			}
+
+			r.Achievements = append(r.Achievements, a.Name)
+			p.Achievements = append(p.Achievements, a.Name)
+
+			g.chat.Public(fmt.Sprintf(text_ACHIEVEMENT, p.Nick, a.Name, a.Description))
+		}
+
+	}
+
+	g.save(storage_RECORDS, g.Records)
+
+}
@@ -0,0 +1,199 @@
+package ptb
+
+import (
+	"strings"
+	"time"
+)
+
+// Kinds of scheduled actions
+const (
+	action_UNBAN  = "unban"  // Unban a player.
+	action_UNMUTE = "unmute" // Unmute a player.
+)
+
+// storage key for scheduled actions
+const storage_ACTIONS = "actions"
+
+// Action is a moderation action scheduled by the game, like an unban.
+// Actions are saved to storage so they survive restarts.
+type Action struct {
+	Kind string    // What to do.
+	Nick string    // Nickname of the player.
+	Due  time.Time // Time the action runs.
+
+	timer *time.Timer
+}
+
+// schedule runs an action for given nickname after given duration.
+func (g *Game) schedule(kind, nick string, d time.Duration) {
+
+	a := &Action{Kind: kind, Nick: nick, Due: time.Now().Add(d)}
+
+	g.Actions = append(g.Actions, a)
+	g.wait(a)
+
+	g.save(storage_ACTIONS, g.Actions)
+
+}
+
+// wait sets the timer for an action, overdue actions run right away.
+func (g *Game) wait(a *Action) {
+
+	a.timer = time.AfterFunc(a.Due.Sub(time.Now()), func() {
+
+		g.mutex.Lock()
+		pending := g.remove(a)
+		g.mutex.Unlock()
+
+		if pending {
+			g.run(a)
+		}
+	})
+
+}
+
+// remove takes an action from the queue, returns false if it wasn't there.
+func (g *Game) remove(a *Action) bool {
+
+	for i, b := range g.Actions {
+		if a == b {
+			g.Actions = append(g.Actions[:i], g.Actions[i+1:]...)
+			g.save(storage_ACTIONS, g.Actions)
+			return true
+		}
+	}
+
+	return false
+}
+
+// run performs an action, the caller shouldn't hold the game lock.
+func (g *Game) run(a *Action) {
+
+	switch a.Kind {
+
+	case action_UNBAN:
+		g.chat.UnBan(a.Nick)
+
+	case action_UNMUTE:
+		if muter, ok := g.chat.Chat.(Muter); ok {
+			muter.UnMute(a.Nick)
+		}
+
+	}
+
+}
+
+// Resume loads actions scheduled before a restart from storage, actions
+// that should have run while we were away run right away.
+// Call this after setting Storage, calling it again reloads the actions.
+func (g *Game) Resume() {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// Nothing to load, scheduled actions are already waiting.
+	if g.Storage == nil {
+		return
+	}
+
+	var actions []*Action
+	g.load(storage_ACTIONS, &actions)
+
+	// Storage has every scheduled action, replace them so none run twice.
+	for _, a := range g.Actions {
+		a.timer.Stop()
+	}
+
+	g.Actions = actions
+
+	for _, a := range actions {
+		g.wait(a)
+	}
+
+}
+
+// Pending returns a copy of the scheduled actions.
+func (g *Game) Pending() []Action {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	actions := make([]Action, len(g.Actions))
+	for i, a := range g.Actions {
+		actions[i] = Action{Kind: a.Kind, Nick: a.Nick, Due: a.Due}
+	}
+
+	return actions
+}
+
+// Cancel removes scheduled actions for given nickname without running them,
+// or only actions of given kind if kind isn't empty.
+// Returns the number of cancelled actions.
+func (g *Game) Cancel(kind, nick string) (n int) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, a := range g.find(kind, nick) {
+		a.timer.Stop()
+		g.remove(a)
+		n++
+	}
+
+	return
+}
+
+// find returns scheduled actions for given nickname and kind.
+func (g *Game) find(kind, nick string) []*Action {
+
+	found := make([]*Action, 0, 1)
+
+	for _, a := range g.Actions {
+		if (kind == "" || a.Kind == kind) && strings.EqualFold(a.Nick, nick) {
+			found = append(found, a)
+		}
+	}
+
+	return found
+}
+
+// Shutdown stops the game and the timers for scheduled actions, like unbans.
+// Actions stay in storage and run after Resume, without storage they run
+// right away so nobody stays banned.
+// Returns once all queued messages are sent, the game can't send any more
+// after this.
+func (g *Game) Shutdown() {
+
+	g.mutex.Lock()
+	g.abort()
+	g.mutex.Unlock()
+
+	// Queued punishments might still schedule actions.
+	if g.chat != nil {
+		g.chat.Flush()
+	}
+
+	g.mutex.Lock()
+
+	actions := g.Actions
+	lift := g.Storage == nil
+
+	for _, a := range actions {
+		a.timer.Stop()
+	}
+
+	g.Actions = nil
+
+	g.mutex.Unlock()
+
+	if lift {
+		for _, a := range actions {
+			g.run(a)
+		}
+	}
+
+	if g.chat != nil {
+		g.chat.close()
+	}
+
+}
@@ -0,0 +1,235 @@
+package ptb
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Authorizer can be implemented by a Chat that knows who may use admin
+// commands, like channel operators.
+type Authorizer interface {
+	IsAdmin(nick string) bool // True if given nickname may control the game.
+}
+
+// authorized returns true if given nickname may use admin commands.
+func (g *Game) authorized(nick string) bool {
+
+	g.mutex.Lock()
+
+	key, stable := g.lookup(nick)
+	listed := false
+
+	// Nicknames only count for players we can't identify, anyone could
+	// take the nickname of an admin.
+	for _, admin := range g.Config.Admins {
+		if (stable && admin == key) || (!stable && sanitizeNick(admin) == key) {
+			listed = true
+		}
+	}
+
+	g.mutex.Unlock()
+
+	if listed {
+		return true
+	}
+
+	// Asking the chat might take a while, don't hold the game lock.
+	if a, ok := g.chat.Chat.(Authorizer); ok {
+		return a.IsAdmin(nick)
+	}
+
+	return false
+}
+
+// admin handles admin commands like "!bomb abort".
+func (g *Game) admin(sender string, args []string) {
+
+	if !g.authorized(sender) {
+		g.chat.Private(sender, text_ADMIN_DENIED)
+		return
+	}
+
+	if len(args) == 0 {
+		g.chat.Private(sender, text_ADMIN_HELP)
+		return
+	}
+
+	switch args[0] {
+
+	case admin_START:
+		go g.Start()
+
+	case admin_ABORT:
+		if !g.Abort() {
+			g.chat.Private(sender, text_ADMIN_NOT_PLAYING)
+		}
+
+	case admin_KICK:
+		if len(args) > 1 && !g.Remove(args[1]) {
+			g.chat.Private(sender, fmt.Sprintf(text_ADMIN_NOT_PLAYER, args[1]))
+		}
+
+	case admin_CONFIG:
+		if len(args) < 4 || args[1] != admin_SET {
+			g.chat.Private(sender, text_ADMIN_HELP)
+			return
+		}
+		value := strings.Join(args[3:], " ")
+		if err := g.Configure(args[2], value); err != nil {
+			g.chat.Private(sender, fmt.Sprintf(text_ADMIN_CONFIG_ERROR, args[2], err))
+		} else {
+			g.chat.Private(sender, fmt.Sprintf(text_ADMIN_CONFIG_SET, args[2], value))
+		}
+
+	case admin_UNBAN:
+		if len(args) > 1 {
+			g.Unban(args[1])
+			g.chat.Private(sender, fmt.Sprintf(text_ADMIN_UNBANNED, args[1]))
+		}
+
+	case admin_REVEAL:
+		if !g.Reveal() {
+			g.chat.Private(sender, text_ADMIN_REVEAL_RUNNING)
+		}
+
+	case admin_PROTECT, admin_UNPROTECT:
+		if len(args) > 1 {
+			g.Protect(args[1], args[0] == admin_PROTECT)
+			g.chat.Private(sender, fmt.Sprintf(text_ADMIN_PROTECTED, args[1], args[0] == admin_PROTECT))
+		}
+
+	case admin_PENDING:
+		g.listPending(sender)
+
+	case admin_CANCEL:
+		if len(args) > 1 {
+			n := g.Cancel("", args[1])
+			g.chat.Private(sender, fmt.Sprintf(text_ADMIN_CANCELLED, n, args[1]))
+		}
+
+	default:
+		g.chat.Private(sender, text_ADMIN_HELP)
+
+	}
+
+}
+
+// Abort stops a running game without results, players can also be joining.
+// Returns false if no game is being played.
+func (g *Game) Abort() bool {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.state != state_PLAYING && g.state != state_WARMUP {
+		return false
+	}
+
+	g.abort()
+
+	return true
+}
+
+// Remove takes a player out of the game, like they left the room.
+// Returns false if nobody with given nickname is playing.
+func (g *Game) Remove(nick string) bool {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.state != state_WARMUP && g.state != state_PLAYING {
+		return false
+	}
+
+	p := g.byNick(nick)
+
+	if p == nil || !p.active() {
+		return false
+	}
+
+	g.chat.Public(fmt.Sprintf(text_ADMIN_REMOVED, p.Nick))
+
+	g.leave(p)
+
+	return true
+}
+
+// Configure changes a setting by name, see Config.Set.
+func (g *Game) Configure(key, value string) error {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if err := g.Config.Set(key, value); err != nil {
+		return err
+	}
+
+	if g.chat != nil {
+		g.chat.configure(&g.Config)
+	}
+
+	return nil
+}
+
+// Unban lifts a ban right away, scheduled unbans for this nickname are
+// dropped.
+func (g *Game) Unban(nick string) {
+
+	g.mutex.Lock()
+
+	for _, a := range g.find(action_UNBAN, nick) {
+		a.timer.Stop()
+		g.remove(a)
+	}
+
+	g.mutex.Unlock()
+
+	g.chat.UnBan(nick)
+
+}
+
+// Reveal tells everyone what the last bomb was like.
+// Returns false while a game is running, or if there was no game yet.
+func (g *Game) Reveal() bool {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.IsActive() || g.bomb == nil || g.bomb.kind == nil || g.Ended.Before(g.Started) {
+		return false
+	}
+
+	kind := "real"
+	if g.bomb.fake {
+		kind = "fake"
+	}
+
+	defusable := "couldn't be defused"
+	if g.bomb.defusable && g.bomb.puzzle != nil {
+		defusable = fmt.Sprintf("could be defused with a %s puzzle", g.bomb.puzzle.Name())
+	}
+
+	timer := g.bomb.detonation.Sub(g.playing) / time.Second * time.Second
+
+	g.chat.Public(fmt.Sprintf(text_ADMIN_REVEAL, kind, g.bomb.kind.Name(), timer, defusable))
+
+	return true
+}
+
+// listPending sends the scheduled actions to given nickname.
+func (g *Game) listPending(nick string) {
+
+	actions := g.Pending()
+
+	if len(actions) == 0 {
+		g.chat.Private(nick, text_ADMIN_PENDING_NONE)
+		return
+	}
+
+	for _, a := range actions {
+		due := a.Due.Sub(time.Now()) / time.Second * time.Second
+		g.chat.Private(nick, fmt.Sprintf(text_ADMIN_PENDING, a.Kind, a.Nick, due))
+	}
+
+}
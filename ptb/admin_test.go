@@ -0,0 +1,38 @@
+package ptb
+
+import (
+	"testing"
+)
+
+func TestAuthorized(t *testing.T) {
+
+	g, _ := newTestGame()
+	g.Config.Admins = []string{"@alice:x", "Bob"}
+
+	tests := []struct {
+		nick       string
+		id         string // Identity of the sender, empty if unknown.
+		authorized bool
+	}{
+		// Identified senders only count by ID.
+		{"Alice", "@alice:x", true},
+		{"Mallory", "@alice:x", true},
+		{"Bob", "@mallory:x", false},
+		// Nicknames count for senders we can't identify.
+		{"Bob", "", true},
+		{" BOB", "", true},
+		{"Alice", "", false},
+		{"Carol", "", false},
+	}
+
+	for _, test := range tests {
+
+		g.Identify(test.nick, test.id)
+
+		if g.authorized(test.nick) != test.authorized {
+			t.Errorf("%q %q: authorized should be %v", test.nick, test.id, test.authorized)
+		}
+
+	}
+
+}
@@ -0,0 +1,214 @@
+package ptb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Betting
+const (
+	tweak_BETS          = true // Allow spectators to bet.
+	tweak_BET_BALANCE   = 100  // Starting balance for new spectators.
+	tweak_BET_FAKE      = 500  // Payout in percent of the bet for a fake bomb.
+	tweak_BET_REAL      = 110  // Payout in percent of the bet for a real bomb.
+	tweak_BET_DEFUSED   = 400  // Payout in percent of the bet for a defused bomb.
+	tweak_BET_LOSER_MUL = 100  // Payout in percent of the bet per player for guessing the loser.
+)
+
+// Kinds of bets
+const (
+	bet_LOSER   = "loser"   // Given player explodes.
+	bet_FAKE    = "fake"    // The bomb is fake.
+	bet_REAL    = "real"    // The bomb is real.
+	bet_DEFUSED = "defused" // The bomb gets defused.
+)
+
+// storage key for spectator balances
+const storage_BALANCES = "balances"
+
+// Bet is a wager placed by a spectator.
+type Bet struct {
+	Nick   string // Nickname of the spectator.
+	Kind   string // Kind of bet.
+	Target string // Nickname of the player expected to explode, for loser bets.
+	Amount int64  // Amount of currency on the line.
+	Payout int64  // Amount paid out after the game.
+
+	id     string // Key of the spectator.
+	target string // Key of the player expected to explode.
+}
+
+// balances returns spectator balances, loading them from storage if needed.
+func (g *Game) balances() map[string]int64 {
+
+	if g.Balances == nil {
+		g.Balances = make(map[string]int64)
+		g.load(storage_BALANCES, &g.Balances)
+	}
+
+	return g.Balances
+}
+
+// balance returns the balance for given player key.
+// New spectators get a starting balance.
+func (g *Game) balance(nick string) int64 {
+
+	b, known := g.balances()[nick]
+	if !known {
+		b = tweak_BET_BALANCE
+		g.balances()[nick] = b
+	}
+
+	return b
+}
+
+// PlaceBet lets a spectator bet on the outcome of the game.
+// Target is only used for bets on the loser.
+func (g *Game) PlaceBet(nick, amount, kind, target string) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	s := g.key(nick)
+
+	// Fast path, only spectators can bet during a game.
+	if !tweak_BETS || !g.IsActive() || g.Players[s] != nil {
+		return
+	}
+
+	n, err := strconv.ParseInt(amount, 10, 64)
+	if err != nil || n <= 0 {
+		g.chat.Private(nick, text_BET_INVALID)
+		return
+	}
+
+	if n > g.balance(s) {
+		g.chat.Private(nick, fmt.Sprintf(text_BET_FUNDS, g.balance(s)))
+		return
+	}
+
+	b := &Bet{Nick: nick, Kind: strings.ToLower(kind), Amount: n, id: s}
+
+	switch b.Kind {
+
+	case bet_LOSER:
+		t, _ := g.resolve(target)
+		if t == nil || !t.active() {
+			g.chat.Private(nick, text_BET_INVALID)
+			return
+		}
+		b.Target = t.Nick
+		b.target = t.ID
+
+	case bet_FAKE, bet_REAL, bet_DEFUSED:
+
+	default:
+		g.chat.Private(nick, text_BET_INVALID)
+		return
+
+	}
+
+	g.balances()[s] -= n
+	g.Bets = append(g.Bets, b)
+
+	if b.Target != "" {
+		g.chat.Public(fmt.Sprintf(text_BET_PLACED_LOSER, nick, n, b.Target))
+	} else {
+		g.chat.Public(fmt.Sprintf(text_BET_PLACED, nick, n, b.Kind))
+	}
+
+}
+
+// Balance privately shows a spectator's balance.
+func (g *Game) Balance(nick string) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !tweak_BETS {
+		return
+	}
+
+	g.chat.Private(nick, fmt.Sprintf(text_BET_BALANCE, g.balance(g.key(nick))))
+
+}
+
+// refundBets returns bets to spectators, for all bets if id is empty.
+func (g *Game) refundBets(id string) {
+
+	bets := g.Bets[:0]
+	refunded := false
+
+	for _, b := range g.Bets {
+		if id != "" && b.id != id {
+			bets = append(bets, b)
+			continue
+		}
+		g.balances()[b.id] += b.Amount
+		refunded = true
+	}
+
+	g.Bets = bets
+
+	if refunded {
+		g.save(storage_BALANCES, g.Balances)
+	}
+
+}
+
+// settleBets pays out winning bets after the game ended.
+func (g *Game) settleBets() {
+
+	if len(g.Bets) == 0 {
+		return
+	}
+
+	// Find out who exploded.
+	loser := ""
+	if v := g.victim(); v != nil {
+		loser = v.ID
+	}
+
+	winners := make([]string, 0, len(g.Bets))
+
+	for _, b := range g.Bets {
+
+		var percent int64
+
+		switch b.Kind {
+		case bet_LOSER:
+			if loser != "" && b.target == loser {
+				percent = tweak_BET_LOSER_MUL * int64(len(g.Players))
+			}
+		case bet_FAKE:
+			if g.bomb.fake {
+				percent = tweak_BET_FAKE
+			}
+		case bet_REAL:
+			if !g.bomb.fake {
+				percent = tweak_BET_REAL
+			}
+		case bet_DEFUSED:
+			if g.bomb.defused {
+				percent = tweak_BET_DEFUSED
+			}
+		}
+
+		if percent == 0 {
+			continue
+		}
+
+		b.Payout = b.Amount * percent / 100
+		g.balances()[b.id] += b.Payout
+
+		winners = append(winners, fmt.Sprintf("%s (%d)", b.Nick, b.Payout))
+	}
+
+	if len(winners) > 0 {
+		g.chat.Public(fmt.Sprintf(text_BET_WINNERS, strings.Join(winners, ", ")))
+	}
+
+	g.save(storage_BALANCES, g.Balances)
+
+}
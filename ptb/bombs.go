@@ -0,0 +1,183 @@
+package ptb
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Bomb variants
+const (
+	tweak_STICKY_TIME      = 15 // Time a sticky bomb can't be thrown in seconds.
+	tweak_COUNTDOWN_PERIOD = 30 // Time between countdown announcements in seconds.
+)
+
+// BombType changes the behaviour of the bomb during a single game.
+// Embed BasicBomb to only implement the methods you need.
+type BombType interface {
+	Name() string                    // Name of this variant.
+	Arm(g *Game)                     // Called when the game starts.
+	Tick(g *Game)                    // Called on every game tick.
+	CanThrow(g *Game) bool           // True if the holder can throw the bomb.
+	Cut(g *Game, outcome uint8) bool // Called when a wire is cut, true if the bomb handled the outcome.
+	Explode(g *Game)                 // Called after the bomb exploded in the holder's face.
+}
+
+// BombVariant is a bomb type that can be chosen for a game.
+type BombVariant struct {
+	Weight int             // Relative chance this variant is chosen.
+	New    func() BombType // Creates a bomb for a new game.
+}
+
+// DefaultBombs returns the bomb variants used by a new game.
+func DefaultBombs() []BombVariant {
+	return []BombVariant{
+		{60, func() BombType { return new(BasicBomb) }},
+		{10, func() BombType { return &StickyBomb{Duration: tweak_STICKY_TIME * time.Second} }},
+		{10, func() BombType { return new(ClusterBomb) }},
+		{10, func() BombType { return &CountdownBomb{Period: tweak_COUNTDOWN_PERIOD * time.Second} }},
+		{10, func() BombType { return new(ShrapnelBomb) }},
+	}
+}
+
+// chooseBomb returns a new bomb of a random variant, respecting weights.
+func chooseBomb(variants []BombVariant) BombType {
+
+	total := 0
+	for _, v := range variants {
+		if v.Weight > 0 {
+			total = total + v.Weight
+		}
+	}
+
+	if total > 0 {
+		n := rand.Intn(total)
+		for _, v := range variants {
+			if v.Weight <= 0 {
+				continue
+			}
+			if n < v.Weight {
+				return v.New()
+			}
+			n = n - v.Weight
+		}
+	}
+
+	return new(BasicBomb)
+}
+
+// BasicBomb is a bomb without special behaviour.
+type BasicBomb struct{}
+
+func (b *BasicBomb) Name() string                    { return "basic" }
+func (b *BasicBomb) Arm(g *Game)                     {}
+func (b *BasicBomb) Tick(g *Game)                    {}
+func (b *BasicBomb) CanThrow(g *Game) bool           { return true }
+func (b *BasicBomb) Cut(g *Game, outcome uint8) bool { return false }
+func (b *BasicBomb) Explode(g *Game)                 {}
+
+// StickyBomb can't be thrown during the first seconds of each turn.
+type StickyBomb struct {
+	BasicBomb
+	Duration time.Duration // Time the bomb sticks to a new holder.
+}
+
+func (b *StickyBomb) Name() string { return "sticky" }
+
+func (b *StickyBomb) Arm(g *Game) {
+	g.chat.Public(fmt.Sprintf(text_BOMB_STICKY, b.Duration/time.Second))
+}
+
+func (b *StickyBomb) CanThrow(g *Game) bool {
+	return g.turn == nil || time.Now().Sub(g.turn.Time) >= b.Duration
+}
+
+// ClusterBomb splits in two when a wrong wire is cut. One part explodes in
+// the face of the player defusing, the other lands with a random player and
+// keeps ticking.
+type ClusterBomb struct {
+	BasicBomb
+	split bool // True if the bomb already split.
+}
+
+func (b *ClusterBomb) Name() string { return "cluster" }
+
+func (b *ClusterBomb) Arm(g *Game) {
+	g.chat.Public(text_BOMB_CLUSTER)
+}
+
+func (b *ClusterBomb) Cut(g *Game, outcome uint8) bool {
+
+	if outcome != defuse_EXPLODE || b.split || g.bomb.fake {
+		return false
+	}
+
+	p := g.bomb.location
+	target := g.randomPlayer(p)
+
+	// Nobody left to catch the other part.
+	if target == nil {
+		return false
+	}
+
+	b.split = true
+	p.Dead = true
+
+	g.chat.Public(fmt.Sprintf(text_BOMB_CLUSTER_SPLIT, target.Nick))
+	g.punish(p)
+
+	g.nextTurn(target)
+	g.bomb.randomize(20*time.Second, 60*time.Second)
+
+	return true
+}
+
+// CountdownBomb announces the time left till detonation.
+type CountdownBomb struct {
+	BasicBomb
+	Period time.Duration // Time between announcements.
+	last   time.Time     // Last announcement.
+}
+
+func (b *CountdownBomb) Name() string { return "countdown" }
+
+func (b *CountdownBomb) Arm(g *Game) {
+	g.chat.Public(text_BOMB_COUNTDOWN)
+	b.last = time.Now()
+}
+
+func (b *CountdownBomb) Tick(g *Game) {
+
+	if time.Now().Sub(b.last) < b.Period {
+		return
+	}
+
+	b.last = time.Now()
+
+	remaining := g.bomb.detonation.Sub(time.Now()) / time.Second
+	g.chat.Public(fmt.Sprintf(text_BOMB_COUNTDOWN_TICK, remaining))
+}
+
+// ShrapnelBomb also takes out the player that threw it to the holder.
+type ShrapnelBomb struct {
+	BasicBomb
+}
+
+func (b *ShrapnelBomb) Name() string { return "shrapnel" }
+
+func (b *ShrapnelBomb) Arm(g *Game) {
+	g.chat.Public(text_BOMB_SHRAPNEL)
+}
+
+func (b *ShrapnelBomb) Explode(g *Game) {
+
+	if g.turn == nil || g.turn.source == nil || g.turn.source.Dead {
+		return
+	}
+
+	p := g.turn.source
+	p.Dead = true
+
+	g.chat.Public(fmt.Sprintf(text_BOMB_SHRAPNEL_HIT, p.Nick))
+	g.punish(p)
+}
@@ -0,0 +1,269 @@
+package ptb
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds game settings that can be changed between rounds.
+type Config struct {
+	MinPlayers int // Minimum number of players.
+
+	MaxHold     time.Duration // Maximum time a player can hold the bomb, 0 disables.
+	MaxHoldDrop bool          // Drop the bomb instead of passing it to a random player.
+	HoldWarning time.Duration // Warn the holder this long before the bomb is taken away.
+
+	Sounds           bool          // Play bomb sounds while the game is running.
+	SoundMinInterval time.Duration // Minimum time between bomb sounds.
+	SoundMaxInterval time.Duration // Maximum time between bomb sounds.
+
+	DefuseCuts    int           // Number of answers a player can give while defusing.
+	DefuseTimeout time.Duration // Time to give an answer while defusing.
+	DefusePenalty uint8         // Outcome when a player runs out of time while defusing.
+	Cooperative   bool          // Send hints to other players while someone is defusing.
+	HintWindow    time.Duration // Time other players have to share their hint.
+
+	Items      bool          // Hand out items to players.
+	ItemChance int           // Chance to receive an item: 0=never; 99=always.
+	FreezeTime time.Duration // Time a frozen player can't throw.
+
+	Bombs   []BombVariant   // Bomb variants to choose from.
+	Puzzles []PuzzleVariant // Defuse minigames to choose from.
+
+	Achievements []Achievement // Achievements players can unlock.
+
+	FirstHolder   int           // Strategy to choose who gets the bomb first.
+	HandicapHold  time.Duration // Maximum hold time taken off per consecutive win.
+	HandicapScore int           // Score reduction in percent per consecutive win.
+
+	LateJoin    int           // Policy for players joining after the game started.
+	LateWindow  time.Duration // Time players can join after the game started, for LateWindow.
+	LatePenalty int           // Score reduction in percent for late joiners.
+
+	DeserterExplode bool // Explode the bomb when the holder leaves, instead of dropping it.
+	DeserterPenalty int  // Score reduction in percent for players that left during the game.
+	AbortOnLeave    bool // Abort the game instead of ending it early when too few players are left.
+
+	RejoinGrace time.Duration // Time a deserter can rejoin and keep their progress.
+
+	TargetMatch int // How throw targets are matched to players.
+
+	Punisher Punisher // Punishment for players the bomb exploded on.
+
+	CommandBurst    int           // Commands a player can send in a row, 0 disables.
+	CommandInterval time.Duration // Time to earn another command.
+	RoomBurst       int           // Commands all players together can send in a row, 0 disables.
+	RoomInterval    time.Duration // Time to earn another command for the room.
+	LimitWarning    bool          // Warn players privately when their commands are ignored.
+
+	OutboundBurst    int           // Messages the bot can send in a row, 0 disables.
+	OutboundInterval time.Duration // Time to earn another message.
+	CoalesceLength   int           // Maximum length of messages combined into one line, 0 disables.
+
+	Admins    []string // Nicknames or identities allowed to use admin commands.
+	Protected []string // Nicknames or identities the game may not kick or ban.
+}
+
+// DefaultConfig returns the settings used by a new game.
+func DefaultConfig() Config {
+	return Config{
+		MinPlayers: tweak_MIN_PLAYERS,
+
+		MaxHold:     tweak_MAX_HOLD * time.Second,
+		MaxHoldDrop: tweak_MAX_HOLD_DROP,
+		HoldWarning: tweak_HOLD_WARNING * time.Second,
+
+		Sounds:           tweak_SOUNDS,
+		SoundMinInterval: tweak_SOUND_MIN_INTERVAL * time.Second,
+		SoundMaxInterval: tweak_SOUND_MAX_INTERVAL * time.Second,
+
+		DefuseCuts:    tweak_DEFUSE_CUTS,
+		DefuseTimeout: tweak_DEFUSE_TIME * time.Second,
+		DefusePenalty: tweak_DEFUSE_PENALTY,
+		Cooperative:   tweak_COOPERATIVE,
+		HintWindow:    tweak_HINT_WINDOW * time.Second,
+
+		Items:      tweak_ITEMS,
+		ItemChance: tweak_ITEM_CHANCE,
+		FreezeTime: tweak_FREEZE_TIME * time.Second,
+
+		Bombs:   DefaultBombs(),
+		Puzzles: DefaultPuzzles(),
+
+		Achievements: DefaultAchievements(),
+
+		FirstHolder:   tweak_FIRST_HOLDER,
+		HandicapHold:  tweak_HANDICAP_HOLD * time.Second,
+		HandicapScore: tweak_HANDICAP_SCORE,
+
+		LateJoin:    tweak_LATE_JOIN,
+		LateWindow:  tweak_LATE_WINDOW * time.Second,
+		LatePenalty: tweak_LATE_PENALTY,
+
+		DeserterExplode: tweak_DESERTER_EXPLODE,
+		DeserterPenalty: tweak_DESERTER_PENALTY,
+		AbortOnLeave:    tweak_ABORT_ON_LEAVE,
+
+		RejoinGrace: tweak_REJOIN_GRACE * time.Second,
+
+		TargetMatch: tweak_TARGET_MATCH,
+
+		Punisher: DefaultPunisher(),
+
+		CommandBurst:    tweak_COMMAND_BURST,
+		CommandInterval: tweak_COMMAND_INTERVAL * time.Second,
+		RoomBurst:       tweak_ROOM_BURST,
+		RoomInterval:    tweak_ROOM_INTERVAL * time.Second,
+		LimitWarning:    tweak_LIMIT_WARNING,
+
+		OutboundBurst:    tweak_OUTBOUND_BURST,
+		OutboundInterval: tweak_OUTBOUND_INTERVAL * time.Second,
+		CoalesceLength:   tweak_COALESCE_LENGTH,
+	}
+}
+
+// Names for policy settings
+var (
+	firstHolders  = map[string]int{"joined": FirstJoined, "random": FirstRandom, "rating": FirstLowestRated, "winner": FirstWinner, "loser": FirstLoser}
+	lateJoins     = map[string]int{"allow": LateAllow, "never": LateNever, "window": LateWindow, "dropped": LateDropped}
+	targetMatches = map[string]int{"strict": MatchStrict, "loose": MatchLoose, "prefix": MatchPrefix}
+)
+
+// Set changes a setting by name, like "min_players". Durations are given in
+// seconds, policies by name.
+func (c *Config) Set(key, value string) error {
+
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	switch strings.ToLower(key) {
+
+	case "min_players":
+		return setInt(&c.MinPlayers, value, 2)
+	case "max_hold":
+		return setSeconds(&c.MaxHold, value)
+	case "max_hold_drop":
+		return setBool(&c.MaxHoldDrop, value)
+	case "hold_warning":
+		return setSeconds(&c.HoldWarning, value)
+	case "sounds":
+		return setBool(&c.Sounds, value)
+	case "defuse_cuts":
+		return setInt(&c.DefuseCuts, value, 1)
+	case "defuse_timeout":
+		return setSeconds(&c.DefuseTimeout, value)
+	case "cooperative":
+		return setBool(&c.Cooperative, value)
+	case "hint_window":
+		return setSeconds(&c.HintWindow, value)
+	case "items":
+		return setBool(&c.Items, value)
+	case "item_chance":
+		return setInt(&c.ItemChance, value, 0)
+	case "freeze_time":
+		return setSeconds(&c.FreezeTime, value)
+	case "first_holder":
+		return setPolicy(&c.FirstHolder, value, firstHolders)
+	case "handicap_hold":
+		return setSeconds(&c.HandicapHold, value)
+	case "handicap_score":
+		return setInt(&c.HandicapScore, value, 0)
+	case "late_join":
+		return setPolicy(&c.LateJoin, value, lateJoins)
+	case "late_window":
+		return setSeconds(&c.LateWindow, value)
+	case "late_penalty":
+		return setInt(&c.LatePenalty, value, 0)
+	case "deserter_explode":
+		return setBool(&c.DeserterExplode, value)
+	case "deserter_penalty":
+		return setInt(&c.DeserterPenalty, value, 0)
+	case "abort_on_leave":
+		return setBool(&c.AbortOnLeave, value)
+	case "rejoin_grace":
+		return setSeconds(&c.RejoinGrace, value)
+	case "target_match":
+		return setPolicy(&c.TargetMatch, value, targetMatches)
+	case "command_burst":
+		return setInt(&c.CommandBurst, value, 0)
+	case "command_interval":
+		return setSeconds(&c.CommandInterval, value)
+	case "room_burst":
+		return setInt(&c.RoomBurst, value, 0)
+	case "room_interval":
+		return setSeconds(&c.RoomInterval, value)
+	case "limit_warning":
+		return setBool(&c.LimitWarning, value)
+	case "outbound_burst":
+		return setInt(&c.OutboundBurst, value, 0)
+	case "outbound_interval":
+		return setSeconds(&c.OutboundInterval, value)
+	case "coalesce_length":
+		return setInt(&c.CoalesceLength, value, 0)
+	case "punisher":
+		p, ok := ParsePunisher(value)
+		if !ok {
+			return errors.New("unknown punishment")
+		}
+		c.Punisher = p
+		return nil
+
+	}
+
+	return errors.New("unknown setting")
+}
+
+// setInt parses a number of at least min.
+func setInt(field *int, value string, min int) error {
+
+	n, err := strconv.Atoi(value)
+
+	if err != nil || n < min {
+		return errors.New("invalid number")
+	}
+
+	*field = n
+	return nil
+}
+
+// setSeconds parses a number of seconds.
+func setSeconds(field *time.Duration, value string) error {
+
+	var n int
+
+	if err := setInt(&n, value, 0); err != nil {
+		return err
+	}
+
+	*field = time.Duration(n) * time.Second
+	return nil
+}
+
+// setBool parses on/off values.
+func setBool(field *bool, value string) error {
+
+	switch value {
+	case "on", "true", "yes", "1":
+		*field = true
+	case "off", "false", "no", "0":
+		*field = false
+	default:
+		return errors.New("invalid switch, use on or off")
+	}
+
+	return nil
+}
+
+// setPolicy parses a policy name.
+func setPolicy(field *int, value string, names map[string]int) error {
+
+	n, ok := names[value]
+
+	if !ok {
+		return errors.New("unknown policy")
+	}
+
+	*field = n
+	return nil
+}
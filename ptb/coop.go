@@ -0,0 +1,127 @@
+package ptb
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Words used by every clue, saying them doesn't share anything.
+var clueWords = map[string]bool{
+	"the": true, "right": true, "wire": true, "wires": true, "is": true, "a": true, "an": true,
+	"in": true, "has": true, "number": true, "comes": true, "after": true, "answer": true,
+	"ends": true, "with": true, "digits": true, "between": true, "and": true, "never": true,
+	"cut": true, "if": true, "there": true, "are": true, "more": true, "than": true, "ones": true,
+}
+
+// words splits a message into lowercase words, ignoring punctuation.
+func words(message string) []string {
+	return strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// keyTerms returns the words of a hint that make it useful, like colors
+// and numbers.
+func keyTerms(hint string) []string {
+
+	terms := make([]string, 0, 2)
+
+	for _, w := range words(hint) {
+		if !clueWords[w] {
+			terms = append(terms, w)
+		}
+	}
+
+	return terms
+}
+
+// relays returns true if a message contains one of given key terms.
+func relays(message string, terms []string) bool {
+
+	for _, w := range words(message) {
+		for _, t := range terms {
+			if w == t {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// sendHints privately gives every other player a different clue about the
+// puzzle, they have to share it with the player defusing.
+func (g *Game) sendHints() {
+
+	puzzle, ok := g.bomb.puzzle.(HintPuzzle)
+	if !ok {
+		return
+	}
+
+	hints := puzzle.Hints()
+	p := g.defuse.player
+
+	helpers := make([]*Player, 0, len(g.Players))
+	for _, h := range g.Players {
+		if h != p && h.active() {
+			helpers = append(helpers, h)
+		}
+	}
+
+	if len(hints) == 0 || len(helpers) == 0 {
+		return
+	}
+
+	rand.Shuffle(len(helpers), func(i, j int) { helpers[i], helpers[j] = helpers[j], helpers[i] })
+
+	g.defuse.helpers = make(map[*Player][]string)
+	g.defuse.window = time.Now().Add(g.Config.HintWindow)
+
+	for i, h := range helpers {
+
+		// Everyone gets a different clue, as long as we have them.
+		if i >= len(hints) {
+			break
+		}
+
+		g.defuse.helpers[h] = keyTerms(hints[i])
+		g.chat.Private(h.Nick, fmt.Sprintf(text_HINT, p.Nick, hints[i]))
+	}
+
+	g.chat.Public(fmt.Sprintf(text_HINT_COOP, p.Nick))
+
+}
+
+// hear marks a player as helper if they relay their hint in the channel.
+func (g *Game) hear(nick, message string) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.state != state_PLAYING || g.defuse == nil || g.defuse.helpers == nil {
+		return
+	}
+
+	if time.Now().After(g.defuse.window) {
+		return
+	}
+
+	p, playing := g.Players[g.key(nick)]
+
+	// Only players that received a hint can help.
+	if !playing {
+		return
+	}
+
+	if terms := g.defuse.helpers[p]; terms != nil && relays(message, terms) {
+		g.defuse.helpers[p] = nil
+		p.Helped = true
+
+		// Helpers might earn an item.
+		g.giveItem(p)
+	}
+
+}
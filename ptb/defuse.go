@@ -0,0 +1,436 @@
+package ptb
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defuse minigames
+const (
+	tweak_QUESTION_TIME = 15 // Time to answer a defuse question in seconds.
+)
+
+// Wire colors used in the wire puzzle.
+var wireColors = []string{"red", "blue", "yellow", "green", "white", "black"}
+
+// Puzzle is a defuse minigame, solving it correctly defuses the bomb.
+type Puzzle interface {
+	Name() string                      // Name of this minigame.
+	Intro() []string                   // Messages explaining the puzzle, shown when defusing starts.
+	Solve(answer string) (uint8, bool) // Returns the outcome of given answer, false if the answer is invalid.
+}
+
+// HintPuzzle is a puzzle that can give partial information to other
+// players during a cooperative defuse.
+type HintPuzzle interface {
+	Puzzle
+	Hints() []string // Different clues about the solution.
+}
+
+// PuzzleVariant is a defuse minigame that can be chosen for a game.
+type PuzzleVariant struct {
+	Weight int           // Relative chance this minigame is chosen.
+	New    func() Puzzle // Creates a puzzle for a new game.
+}
+
+// DefaultPuzzles returns the defuse minigames used by a new game.
+func DefaultPuzzles() []PuzzleVariant {
+	return []PuzzleVariant{
+		{60, func() Puzzle { return NewWirePuzzle(tweak_MIN_WIRES, tweak_MAX_WIRES) }},
+		{20, func() Puzzle { return NewCodePuzzle() }},
+		{20, func() Puzzle { return NewQuestionPuzzle(tweak_QUESTION_TIME * time.Second) }},
+	}
+}
+
+// choosePuzzle returns a new puzzle of a random minigame, respecting weights.
+func choosePuzzle(variants []PuzzleVariant) Puzzle {
+
+	total := 0
+	for _, v := range variants {
+		if v.Weight > 0 {
+			total = total + v.Weight
+		}
+	}
+
+	if total > 0 {
+		n := rand.Intn(total)
+		for _, v := range variants {
+			if v.Weight <= 0 {
+				continue
+			}
+			if n < v.Weight {
+				return v.New()
+			}
+			n = n - v.Weight
+		}
+	}
+
+	return NewWirePuzzle(tweak_MIN_WIRES, tweak_MAX_WIRES)
+}
+
+// badOutcome returns a random outcome other than success.
+func badOutcome() uint8 {
+	return uint8(rand.Intn(defuse_SUCCESS))
+}
+
+// numberHints returns clues about a number.
+func numberHints(n int) []string {
+
+	hints := []string{
+		fmt.Sprintf(text_CLUE_DIGITS, len(strconv.Itoa(n))),
+		fmt.Sprintf(text_CLUE_LAST_DIGIT, n%10),
+	}
+
+	if n%2 == 0 {
+		hints = append(hints, text_CLUE_NUMBER_EVEN)
+	} else {
+		hints = append(hints, text_CLUE_NUMBER_ODD)
+	}
+
+	low := n - rand.Intn(10)
+	hints = append(hints, fmt.Sprintf(text_CLUE_RANGE, low, low+10))
+
+	rand.Shuffle(len(hints), func(i, j int) { hints[i], hints[j] = hints[j], hints[i] })
+
+	return hints
+}
+
+// wire is a single wire in the wire puzzle.
+type wire struct {
+	color   string
+	outcome uint8
+}
+
+// WirePuzzle asks the player to cut one of several colored wires.
+// Clues help finding the right one.
+type WirePuzzle struct {
+	wires []wire
+	clues []string
+}
+
+// NewWirePuzzle creates a wire puzzle with a random number of wires.
+func NewWirePuzzle(min, max int) *WirePuzzle {
+
+	w := new(WirePuzzle)
+
+	n := min
+	if max > min {
+		n += rand.Intn(max - min)
+	}
+	if n < 1 {
+		n = 1
+	}
+	w.wires = make([]wire, n)
+
+	blue := 0
+	for i := range w.wires {
+		w.wires[i].color = wireColors[rand.Intn(len(wireColors))]
+		w.wires[i].outcome = badOutcome()
+		if w.wires[i].color == "blue" {
+			blue++
+		}
+	}
+
+	t := rand.Intn(n)
+	w.wires[t].outcome = defuse_SUCCESS
+
+	// Every clue is true for the right wire.
+	w.clues = append(w.clues, fmt.Sprintf(text_CLUE_COLOR, w.wires[t].color))
+
+	if (t+1)%2 == 0 {
+		w.clues = append(w.clues, text_CLUE_EVEN)
+	} else {
+		w.clues = append(w.clues, text_CLUE_ODD)
+	}
+
+	if t < n/2 {
+		w.clues = append(w.clues, text_CLUE_FIRST_HALF)
+	} else {
+		w.clues = append(w.clues, text_CLUE_SECOND_HALF)
+	}
+
+	if t > 0 {
+		w.clues = append(w.clues, fmt.Sprintf(text_CLUE_AFTER, w.wires[t-1].color))
+	}
+
+	// Red wires are deadly when there are a lot of blue ones.
+	if blue > 3 && w.wires[t].color != "red" {
+		for i := range w.wires {
+			if w.wires[i].color == "red" {
+				w.wires[i].outcome = defuse_EXPLODE
+			}
+		}
+		w.clues = append(w.clues, text_CLUE_RED_BLUE)
+	}
+
+	rand.Shuffle(len(w.clues), func(i, j int) { w.clues[i], w.clues[j] = w.clues[j], w.clues[i] })
+
+	return w
+}
+
+func (w *WirePuzzle) Name() string { return "wires" }
+
+func (w *WirePuzzle) Intro() []string {
+
+	labels := make([]string, len(w.wires))
+	for i, wire := range w.wires {
+		labels[i] = fmt.Sprintf("%d:%s", i+1, wire.color)
+	}
+
+	return []string{
+		fmt.Sprintf(text_DEFUSE, len(w.wires), strings.Join(labels, " ")),
+		fmt.Sprintf(text_DEFUSE_CLUE, w.clues[0]),
+	}
+}
+
+// Hints returns the clues that weren't shown to the player defusing.
+func (w *WirePuzzle) Hints() []string {
+	return w.clues[1:]
+}
+
+// Solve cuts the wire with given number, or the only wire with given color.
+func (w *WirePuzzle) Solve(answer string) (uint8, bool) {
+
+	i := -1
+
+	if n, err := strconv.Atoi(answer); err == nil {
+		i = n - 1
+	} else {
+		for j, wire := range w.wires {
+			if wire.color == strings.ToLower(answer) {
+				if i >= 0 {
+					return 0, false
+				}
+				i = j
+			}
+		}
+	}
+
+	// Check if the wire exists
+	if i < 0 || i >= len(w.wires) {
+		return 0, false
+	}
+
+	outcome := w.wires[i].outcome
+	w.wires[i].outcome = defuse_CUT
+
+	return outcome, true
+}
+
+// CodePuzzle is a code lock showing the start of a number sequence, the
+// next number in the sequence opens the lock.
+type CodePuzzle struct {
+	sequence []int
+	code     int
+}
+
+// NewCodePuzzle creates a code lock with a random sequence.
+func NewCodePuzzle() *CodePuzzle {
+
+	c := new(CodePuzzle)
+	c.sequence = make([]int, 4)
+
+	switch rand.Intn(3) {
+
+	case 0: // Arithmetic
+		start, step := rand.Intn(20)+1, rand.Intn(8)+2
+		for i := range c.sequence {
+			c.sequence[i] = start + i*step
+		}
+		c.code = start + len(c.sequence)*step
+
+	case 1: // Geometric
+		start, ratio := rand.Intn(5)+1, rand.Intn(2)+2
+		n := start
+		for i := range c.sequence {
+			c.sequence[i] = n
+			n = n * ratio
+		}
+		c.code = n
+
+	case 2: // Fibonacci-like
+		a, b := rand.Intn(5)+1, rand.Intn(5)+1
+		for i := range c.sequence {
+			c.sequence[i] = a
+			a, b = b, a+b
+		}
+		c.code = a
+
+	}
+
+	return c
+}
+
+func (c *CodePuzzle) Name() string { return "code" }
+
+func (c *CodePuzzle) Intro() []string {
+
+	numbers := make([]string, len(c.sequence))
+	for i, n := range c.sequence {
+		numbers[i] = strconv.Itoa(n)
+	}
+
+	return []string{fmt.Sprintf(text_DEFUSE_CODE, strings.Join(numbers, ", "))}
+}
+
+func (c *CodePuzzle) Hints() []string {
+	return numberHints(c.code)
+}
+
+func (c *CodePuzzle) Solve(answer string) (uint8, bool) {
+
+	n, err := strconv.Atoi(answer)
+
+	// Typos don't count as an attempt.
+	if err != nil {
+		return 0, false
+	}
+
+	if n == c.code {
+		return defuse_SUCCESS, true
+	}
+
+	return badOutcome(), true
+}
+
+// QuestionPuzzle asks a question that has to be answered in time.
+type QuestionPuzzle struct {
+	question string
+	answer   int
+	limit    time.Duration
+	asked    time.Time
+}
+
+// NewQuestionPuzzle creates a random calculation to solve within given time.
+func NewQuestionPuzzle(limit time.Duration) *QuestionPuzzle {
+
+	q := new(QuestionPuzzle)
+	q.limit = limit
+
+	a, b := rand.Intn(20)+2, rand.Intn(20)+2
+
+	switch rand.Intn(3) {
+	case 0:
+		q.question, q.answer = fmt.Sprintf("%d + %d", a, b), a+b
+	case 1:
+		q.question, q.answer = fmt.Sprintf("%d - %d", a+b, b), a
+	case 2:
+		q.question, q.answer = fmt.Sprintf("%d x %d", a, b), a*b
+	}
+
+	return q
+}
+
+func (q *QuestionPuzzle) Name() string { return "question" }
+
+func (q *QuestionPuzzle) Intro() []string {
+	q.asked = time.Now()
+	return []string{fmt.Sprintf(text_DEFUSE_QUESTION, q.limit/time.Second, q.question)}
+}
+
+func (q *QuestionPuzzle) Hints() []string {
+	return numberHints(q.answer)
+}
+
+// Solve checks the answer, correct answers given too late do nothing.
+func (q *QuestionPuzzle) Solve(answer string) (uint8, bool) {
+
+	n, err := strconv.Atoi(answer)
+
+	// Typos don't count as an attempt.
+	if err != nil {
+		return 0, false
+	}
+
+	if n != q.answer {
+		return badOutcome(), true
+	}
+
+	if q.asked.IsZero() || time.Now().Sub(q.asked) > q.limit {
+		return defuse_NOTHING, true
+	}
+
+	return defuse_SUCCESS, true
+}
+
+// defuse is an attempt to defuse the bomb.
+// The bomb is locked to the player until the attempt ends.
+type defuse struct {
+	player   *Player   // Player defusing the bomb
+	cuts     int       // Number of answers left
+	deadline time.Time // Time the next answer has to be given
+
+	helpers map[*Player][]string // Key terms of the hint each helper received, nil once shared
+	window  time.Time            // Time helpers have to share their hint
+}
+
+// checkDefuse applies the penalty if the player defusing didn't answer in time.
+func (g *Game) checkDefuse() {
+
+	if g.defuse == nil || time.Now().Before(g.defuse.deadline) {
+		return
+	}
+
+	g.defuse.cuts = 0
+	g.turn.DefuseExpired = true
+
+	g.chat.Public(fmt.Sprintf(text_DEFUSE_TIMEOUT, g.defuse.player.Nick))
+
+	g.applyDefuse(g.Config.DefusePenalty)
+
+}
+
+// applyDefuse handles the outcome of a defuse answer.
+func (g *Game) applyDefuse(outcome uint8) {
+
+	p := g.defuse.player
+
+	// Some bombs handle wires differently.
+	if g.bomb.kind.Cut(g, outcome) {
+		return
+	}
+
+	// Check the wire function
+	switch outcome {
+
+	case defuse_SUCCESS:
+		g.bomb.defused = true
+		p.Defused = true
+		g.chat.Public(fmt.Sprintf(text_DEFUSE_SUCCESS, p.Nick))
+		g.end()
+		return
+
+	case defuse_NOTHING:
+		g.chat.Public(fmt.Sprintf(text_DEFUSE_NOTHING, p.Nick))
+
+	case defuse_LESS_TIME:
+		g.bomb.randomize(20*time.Second, 60*time.Second)
+		g.chat.Public(text_DEFUSE_LESS_TIME)
+
+	case defuse_MORE_TIME:
+		d := g.bomb.detonation.Sub(time.Now())
+		g.bomb.randomize(d, d+(5*time.Minute))
+		g.chat.Public(text_DEFUSE_MORE_TIME)
+
+	case defuse_EXPLODE:
+		g.end()
+		return
+
+	case defuse_CUT:
+		g.chat.Public(text_DEFUSE_DUPLICATE)
+
+	}
+
+	// Unlock the bomb once all answers are used.
+	if g.defuse.cuts > 0 {
+		g.chat.Public(fmt.Sprintf(text_DEFUSE_CUTS_LEFT, g.defuse.cuts, p.Nick))
+		return
+	}
+
+	g.defuse = nil
+	g.chat.Public(fmt.Sprintf(text_DEFUSE_OVER, p.Nick))
+
+}
@@ -0,0 +1,75 @@
+package ptb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCodePuzzleSolve(t *testing.T) {
+
+	c := &CodePuzzle{sequence: []int{2, 4, 6, 8}, code: 10}
+
+	tests := []struct {
+		answer  string
+		attempt bool
+		success bool
+	}{
+		{"10", true, true},
+		{"12", true, false},
+		{"-10", true, false},
+		{"ten", false, false},
+		{"10.0", false, false},
+		{"", false, false},
+	}
+
+	for _, test := range tests {
+
+		outcome, attempt := c.Solve(test.answer)
+
+		if attempt != test.attempt || (outcome == defuse_SUCCESS) != test.success {
+			t.Errorf("%q: got %d %v, want success %v attempt %v", test.answer, outcome, attempt, test.success, test.attempt)
+		}
+
+	}
+
+}
+
+func TestQuestionPuzzleSolve(t *testing.T) {
+
+	tests := []struct {
+		asked   time.Duration // Time since the question was asked.
+		answer  string
+		attempt bool
+		outcome uint8
+	}{
+		{time.Second, "42", true, defuse_SUCCESS},
+		{time.Minute, "42", true, defuse_NOTHING},
+		{time.Second, "forty-two", false, defuse_NOTHING},
+		{time.Second, " 42", false, defuse_NOTHING},
+	}
+
+	for _, test := range tests {
+
+		q := &QuestionPuzzle{question: "6 x 7", answer: 42, limit: 10 * time.Second, asked: time.Now().Add(-test.asked)}
+
+		outcome, attempt := q.Solve(test.answer)
+
+		if attempt != test.attempt || outcome != test.outcome {
+			t.Errorf("%q after %s: got %d %v, want %d %v", test.answer, test.asked, outcome, attempt, test.outcome, test.attempt)
+		}
+
+	}
+
+	// Wrong answers never defuse the bomb.
+	q := &QuestionPuzzle{question: "6 x 7", answer: 42, limit: 10 * time.Second, asked: time.Now()}
+	if outcome, attempt := q.Solve("41"); outcome == defuse_SUCCESS || !attempt {
+		t.Errorf("wrong answer: got %d %v", outcome, attempt)
+	}
+
+	// Questions that were never asked can't be answered in time.
+	q.asked = time.Time{}
+	if outcome, _ := q.Solve("42"); outcome != defuse_NOTHING {
+		t.Errorf("not asked: got %d", outcome)
+	}
+
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 )
 
 func init() {
@@ -26,6 +27,25 @@ const (
 	tweak_FAKE          = true // Enable fake bombs.
 	tweak_FAKE_CHANCE   = 10   // Chance that a bomb will be fake: 0=never; 99=always.
 	tweak_MIN_PLAYERS   = 4    // Minimum number of players.
+	tweak_TICK          = 5    // Time between game ticks in seconds.
+
+	tweak_DEFUSE_CUTS    = 2                // Number of answers a player can give while defusing.
+	tweak_DEFUSE_TIME    = 30               // Time to give an answer while defusing in seconds.
+	tweak_DEFUSE_PENALTY = defuse_LESS_TIME // Outcome when a player runs out of time while defusing.
+	tweak_COOPERATIVE    = true             // Send hints to other players while someone is defusing.
+	tweak_HINT_WINDOW    = 30               // Time other players have to share their hint in seconds.
+
+	tweak_MAX_HOLD      = 120   // Maximum time a player can hold the bomb in seconds: 0=unlimited.
+	tweak_MAX_HOLD_DROP = false // Drop the bomb instead of passing it to a random player.
+	tweak_HOLD_WARNING  = 20    // Warn the holder this many seconds before the bomb is taken away.
+
+	tweak_SOUNDS             = true // Play bomb sounds while the game is running.
+	tweak_SOUND_MIN_INTERVAL = 10   // Minimum time between bomb sounds in seconds.
+	tweak_SOUND_MAX_INTERVAL = 90   // Maximum time between bomb sounds in seconds.
+
+	tweak_ITEMS       = true // Hand out items to players.
+	tweak_ITEM_CHANCE = 15   // Chance to receive an item when catching the bomb: 0=never; 99=always.
+	tweak_FREEZE_TIME = 20   // Time a frozen player can't throw in seconds.
 
 	tweak_KICK     = true // Kick player on explosion.
 	tweak_BAN      = true // Ban player after explosion. (prevent auto rejoin)
@@ -64,11 +84,13 @@ type Chat interface {
 type bomb struct {
 	fake       bool      // Fake bombs do not actually explode
 	defusable  bool      // True if this bomb can be defused
-	wires      []uint8   // Wire functions
+	puzzle     Puzzle    // Defuse minigame
 	location   *Player   // Player currently holding the bomb
 	detonation time.Time // Detonation time
 	throwTime  time.Time // Last time the bomb was thrown
+	nextSound  time.Time // Next time the bomb makes a sound
 	defused    bool
+	kind       BombType // Variant changing the bomb's behaviour
 }
 
 // randomize sets a random detonation time.
@@ -86,6 +108,12 @@ type Turn struct {
 	Duration      time.Duration // How long did the player keep the bomb?
 	Time          time.Time     // When did this turn happen?
 	DefuseAttempt bool          // Did the player defuse during this turn?
+	Cuts          int           // Number of answers given while defusing.
+	DefuseExpired bool          // Did the player run out of time while defusing?
+	Items         []string      // Items used by any player during this turn.
+	Dropped       bool          // Did the player drop the bomb at the end of this turn?
+	Forced        bool          // Was the bomb taken away after holding it too long?
+	warned        bool          // Was the holder warned about the maximum hold time?
 
 	SourceNick string // Nickname of the source for JSON export.
 	TargetNick string // Nickname of the target for JSON export.
@@ -94,56 +122,114 @@ type Turn struct {
 // player represents a single player in the game
 type Player struct {
 	Nick          string  // Actual nickname of this player
+	ID            string  // Stable identity, or the sanitized nickname if unknown.
 	sanitizedNick string  // Sanitized nickname
+	nickID        bool    // True if the ID is the sanitized nickname
 	Late          bool    // True if this player joined after the game started
 	turns         []*Turn // List of turns this player has played.
 	DefuseAttempt bool    // True if player already tried to defuse.
 	Defused       bool    // Player defused!
-	Dead          bool    // Bomb exploded while the player was holding it.
+	Cuts          int     // Number of answers given while defusing.
+	Helped        bool    // Shared a hint while someone else was defusing.
+
+	Items    map[string]int // Number of items of each kind in the player's inventory.
+	shield   bool           // Blocks the next throw to this player.
+	frozen   time.Time      // Player can't throw until this time.
+	Dead     bool           // Bomb exploded while the player was holding it.
+	Deserted bool           // Player left during the game.
+	left     time.Time      // Time the player left.
 
 	Duration     time.Duration // Total turn duration for JSON export.
 	MeanDuration time.Duration // Mean turn duration for JSON export.
 	Turns        int           // Number of turns for JSON export.
+	Throws       int           // Number of throws for JSON export.
+	Drops        int           // Number of drops for JSON export.
+	Achievements []string      // Achievements unlocked during this game.
+	Rating       float64       // Rating after this game for JSON export.
+	Handicap     int           // Score reduction in percent for JSON export.
+}
+
+// active returns true if the player can still take part in the game.
+func (p *Player) active() bool {
+	return !p.Dead && !p.Deserted
 }
 
 // Game represents a single instance of the game.
 type Game struct {
-	bomb    *bomb              // The bomb used in this game
-	Players map[string]*Player // Players
-	state   uint8              // Game state
-	chat    Chat               // Interface to the chatroom
-	mutex	*sync.Mutex // Mutex for locking game state.
-	first   *Player            // First player to start
-	stop    chan bool          // Indicates the game ended.
-	turn    *Turn              // Current turn, or nil if the bomb was dropped.
-	Started time.Time          // Game start time.
-	Ended   time.Time          // Game end time.
+	bomb       *bomb              // The bomb used in this game
+	Players    map[string]*Player // Players
+	state      uint8              // Game state
+	chat       *outbox            // Interface to the chatroom
+	mutex      *sync.Mutex        // Mutex for locking game state.
+	first      *Player            // First player to start
+	announced  bool               // True if the first player was announced.
+	lastWinner string             // Winner of the last game.
+	lastLoser  string             // Loser of the last game.
+	stop       chan bool          // Indicates the game ended.
+	turn       *Turn              // Current turn, or nil if the bomb was dropped.
+	defuse     *defuse            // Current defuse attempt, or nil if nobody is defusing.
+	early      bool               // True if the game ended before the bomb exploded.
+	ids        map[string]string  // Identities for sanitized nicknames.
+	limits     map[string]*bucket // Command limits per player.
+	room       bucket             // Command limit for all players together.
+	Started    time.Time          // Game start time.
+	playing    time.Time          // Time the bomb was first handed out.
+	Ended      time.Time          // Game end time.
 
 	Scores ScoreBoard // Game results, or nil if a game is currently being played.
-	Scorer ScoreCalc  // Function used to calculate scores.
+	Scorer ScoreCalc  `json:"-"` // Function used to calculate scores.
+
+	Bomb string // Name of the bomb variant for JSON export.
+
+	Config  Config  `json:"-"` // Game settings.
+	Storage Storage `json:"-"` // Storage for data kept across games, or nil to keep it in memory.
+
+	Bets     []*Bet             // Bets placed by spectators during this game.
+	Balances map[string]int64   `json:"-"` // Spectator balances.
+	Records  map[string]*Record `json:"-"` // Player statistics across games.
+
+	Protected map[string]bool `json:"-"` // Players the game may not kick or ban.
+	OptOuts   map[string]bool `json:"-"` // Players that never want to join.
+
+	Actions []*Action `json:"-"` // Scheduled moderation actions, like unbans.
 
 	Turns []*Turn // Complete list of turns for JSON export.
 }
 
 func NewGame(chat Chat) *Game {
 	g := new(Game)
-	g.chat = chat
+	g.mutex = new(sync.Mutex)
+	g.Config = DefaultConfig()
+
+	if chat != nil {
+		g.chat = newOutbox(chat, &g.Config)
+	}
 
 	return g
 }
 
 func (g *Game) nextTurn(next *Player) {
 
+	// Player holding the bomb during the last turn.
+	last := g.bomb.location
+
 	// Finalize last turn.
 	if g.turn != nil {
 		g.turn.target = next
-		g.turn.TargetNick = next.Nick
+		if next != nil {
+			g.turn.TargetNick = next.Nick
+		}
+
+		g.turn.Dropped = (next == nil)
 
 		// Calculate time
 		g.turn.Duration = time.Now().Sub(g.turn.Time)
-		g.turn.source.Duration = g.turn.source.Duration + g.turn.Duration
+		last.Duration = last.Duration + g.turn.Duration
 	}
 
+	// Defusing ends when the bomb moves.
+	g.defuse = nil
+
 	// Bomp dropped, no next turn.
 	if next == nil {
 		g.turn = nil
@@ -151,12 +237,12 @@ func (g *Game) nextTurn(next *Player) {
 		return
 	}
 
-	last := g.turn.source
-
 	// Initialize new turn
 	g.turn = new(Turn)
 	g.turn.source = last
-	g.turn.SourceNick = last.Nick
+	if last != nil {
+		g.turn.SourceNick = last.Nick
+	}
 	g.turn.Time = time.Now()
 
 	// Add pointer to the player's turn list.
@@ -167,13 +253,30 @@ func (g *Game) nextTurn(next *Player) {
 	// TODO: Delete this var and always read location from current turn.
 	g.bomb.location = next
 
+	g.giveItem(next)
+
 	return
 
 }
 
-// sanitizeNick returns a lowercase version of the nickname, stripped from spaces.
+// sanitizeNick returns a lowercase version of the nickname, stripped from spaces
+// and invisible characters. Fullwidth characters are folded to ASCII, but this
+// is not full NFKC normalization: other lookalikes, like accents written as
+// combining marks or letters from other scripts, are still different nicknames.
 func sanitizeNick(nick string) string {
-	return strings.ToLower(strings.Trim(nick, " \t\n\r"))
+	nick = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		if r == '\u3000' {
+			return ' '
+		}
+		if r >= '\uff01' && r <= '\uff5e' {
+			return r - 0xfee0
+		}
+		return r
+	}, nick)
+	return strings.ToLower(strings.TrimFunc(nick, unicode.IsSpace))
 }
 
 // IsActive returns true if there is possible interaction with players.
@@ -184,11 +287,16 @@ func (g *Game) IsActive() bool {
 // Start launches the joining timeslot for a new game!
 func (g *Game) Start() {
 
+	g.mutex.Lock()
+
 	if g.IsActive() || g.chat == nil {
+		g.mutex.Unlock()
 		return
 	}
 
 	g.bomb = new(bomb)
+	g.bomb.kind = chooseBomb(g.Config.Bombs)
+	g.Bomb = g.bomb.kind.Name()
 
 	// Choose fake bombs
 	if tweak_FAKE {
@@ -199,12 +307,7 @@ func (g *Game) Start() {
 	if tweak_DEFUSE {
 		g.bomb.defusable = (rand.Intn(100) <= tweak_DEFUSE_CHANCE)
 
-		n := rand.Intn(tweak_MAX_WIRES-tweak_MIN_WIRES) + tweak_MIN_WIRES
-		g.bomb.wires = make([]uint8, n)
-
-		for i := range g.bomb.wires {
-			g.bomb.wires[i] = uint8(rand.Intn(5))
-		}
+		g.bomb.puzzle = choosePuzzle(g.Config.Puzzles)
 	}
 
 	// Make sure we reset everything before starting a new game.
@@ -212,35 +315,61 @@ func (g *Game) Start() {
 	g.bomb.detonation = time.Now()
 	g.bomb.throwTime = time.Now()
 	g.Players = make(map[string]*Player)
+	g.turn = nil
+	g.defuse = nil
+	g.announced = false
+	g.early = false
 	g.Turns = make([]*Turn, 0, 10)
 	g.Scores = make(ScoreBoard, 0, 10)
+	g.Bets = make([]*Bet, 0, 10)
+	g.limits = nil
+	g.stop = make(chan bool)
 	g.state = state_WARMUP
+	g.chat.configure(&g.Config)
 
 	g.chat.Public(text_START_ATTENTION)
 	g.chat.Public(text_START_JOIN)
 
+	stop := g.stop
+
+	g.mutex.Unlock()
+
 	duration := (tweak_JOIN_DURATION / 5 * time.Second)
 
 	// Explain how the game works during join time.
 	for i := time.Duration(1); i < 5; i++ {
-		g.explain(i, duration*i)
+		if !g.explain(i, duration*i, stop) {
+			return
+		}
 	}
 
 	timer := time.NewTimer(duration * 5)
+	defer timer.Stop()
 
-	// Wait for timer to expire
-	<-timer.C
+	// Wait for timer to expire, unless the game was aborted.
+	select {
+	case <-timer.C:
+	case <-stop:
+		return
+	}
 
 	g.start()
 
 }
 
-func (g *Game) explain(tick, duration time.Duration) {
+// explain waits for given duration and explains part of the game.
+// Returns false if the game was aborted in the meantime.
+func (g *Game) explain(tick, duration time.Duration, stop chan bool) bool {
 
 	timer := time.NewTimer(duration)
+	defer timer.Stop()
 
 	// Wait for timer to expire
-	<-timer.C
+	select {
+	case <-timer.C:
+	case <-stop:
+		return false
+	}
 
 	switch tick {
 
@@ -251,49 +380,79 @@ func (g *Game) explain(tick, duration time.Duration) {
 	case 3:
 		g.chat.Public(text_HELP_DEFUSE)
 	case 4:
-		if len(g.Players) >= tweak_MIN_PLAYERS {
+		g.mutex.Lock()
+		if len(g.Players) >= g.Config.MinPlayers {
+			g.first = g.chooseFirst()
+			g.announced = true
 			g.chat.Public(fmt.Sprintf(text_HELP_START, g.first.Nick))
 		}
+		g.mutex.Unlock()
 
 	}
+
+	return true
 }
 
 // start is an internal method and starts the actual game after the joining timeslot.
 func (g *Game) start() {
 
-	if len(g.Players) < tweak_MIN_PLAYERS {
+	// Ask for operator status before taking the lock, punishments use the
+	// cached result.
+	g.chat.refresh()
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// Aborted right before the joining timeslot ended.
+	if g.state != state_WARMUP {
+		return
+	}
+
+	if len(g.Players) < g.Config.MinPlayers {
 		g.chat.Public(text_START_FAIL)
+		g.refundBets("")
 		g.state = state_INIT
 		return
 	}
 
+	// Players might have joined or left after the announcement, only keep a
+	// random choice that was announced.
+	if !g.announced || g.Config.FirstHolder != FirstRandom {
+		g.first = g.chooseFirst()
+	}
+
 	// Send the bomb to the next player!
 	g.nextTurn(g.first)
 	g.bomb.location = g.first
 
 	g.state = state_PLAYING
+	g.playing = time.Now()
 
 	g.bomb.randomize(tweak_MIN_DURATION*time.Second, tweak_MAX_DURATION*time.Second)
+	g.bomb.kind.Arm(g)
+	g.scheduleSound()
 
 	// Send message.
 	g.chat.Public(fmt.Sprintf(text_START_GO, g.first.Nick))
 
-	ticker := time.NewTicker(10 * time.Second)
+	stop := g.stop
+
+	ticker := time.NewTicker(tweak_TICK * time.Second)
 
 	// Start game ticks
 	go func() {
 
+		defer ticker.Stop()
+
 		for {
 			select {
 
 			case <-ticker.C:
+				g.mutex.Lock()
+				g.tick()
+				g.mutex.Unlock()
 
-				// Check if the bomb has to explode.
-				if time.Now().After(g.bomb.detonation) {
-					g.Stop()
-				}
-
-			case <-g.stop:
+			case <-stop:
 				return
 
 			}
@@ -303,6 +462,31 @@ func (g *Game) start() {
 
 }
 
+// tick is called periodically while the game is being played.
+func (g *Game) tick() {
+
+	if g.state != state_PLAYING {
+		return
+	}
+
+	// Check if the bomb has to explode.
+	if time.Now().After(g.bomb.detonation) {
+		g.end()
+		return
+	}
+
+	// Deserters that didn't come back in time no longer count.
+	if g.checkPlayers(); g.state != state_PLAYING {
+		return
+	}
+
+	g.bomb.kind.Tick(g)
+	g.checkDefuse()
+	g.checkHold()
+	g.checkSound()
+
+}
+
 // Join adds a player to the game.
 // Players can only join during the warmup or playing states.
 func (g *Game) Join(nick string) {
@@ -310,22 +494,47 @@ func (g *Game) Join(nick string) {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
-	s := sanitizeNick(nick)
+	s, stable := g.lookup(nick)
+
+	// Deserters can come back for a while.
+	if p := g.deserter(nick); p != nil {
+		g.rejoin(p, nick)
+		return
+	}
 
 	// Fast path, joining is not allowed or useless.
 	if (g.state != state_WARMUP && g.state != state_PLAYING) || g.Players[s] != nil {
 		return
 	}
+
+	// Some people never want to play.
+	if g.optedOut(nick) {
+		g.chat.Private(nick, text_PLAYER_OPTED_OUT)
+		return
+	}
+
+	// Check if late players are welcome.
+	if g.state == state_PLAYING && !g.lateAllowed() {
+		g.chat.Private(nick, text_PLAYER_JOIN_REFUSED)
+		return
+	}
+
 	// Initialize new player
 	p := new(Player)
 	p.Nick = nick
-	p.sanitizedNick = s
+	p.ID = s
+	p.sanitizedNick = sanitizeNick(nick)
+	p.nickID = !stable
 	p.Late = (g.state == state_PLAYING)
 	p.turns = make([]*Turn, 0, 5)
+	p.Items = make(map[string]int)
 
 	// Append to player map
 	g.Players[s] = p
 
+	// Players can't bet on their own game.
+	g.refundBets(s)
+
 	if len(g.Players) <= 1 {
 		g.first = p
 	}
@@ -346,31 +555,60 @@ func (g *Game) Throw(source, target string) {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
-	source = sanitizeNick(source)
 	p := g.bomb.location
 
 	// Fast path
-	if g.state != state_PLAYING || p == nil || p.sanitizedNick != source {
+	if g.state != state_PLAYING || p == nil || p.ID != g.key(source) {
 		return
 	}
 
-	target = sanitizeNick(target)
+	// The bomb can't be thrown while defusing.
+	if g.defuse != nil {
+		g.chat.Public(fmt.Sprintf(text_DEFUSE_LOCKED, p.Nick))
+		return
+	}
 
-	if source == target {
-		g.chat.Public(fmt.Sprintf(text_BOMB_THROWN_SELF, p.Nick))
+	// Frozen players can't throw.
+	if time.Now().Before(p.frozen) {
+		g.chat.Public(fmt.Sprintf(text_ITEM_FROZEN, p.Nick))
+		return
+	}
+
+	// Some bombs can't be thrown right away.
+	if !g.bomb.kind.CanThrow(g) {
+		g.chat.Public(fmt.Sprintf(text_BOMB_STUCK, p.Nick))
 		return
 	}
 
 	// Attempt to fetch target
-	t, playing := g.Players[target]
+	t, suggestion := g.resolve(target)
+
+	if t == p {
+		g.chat.Public(fmt.Sprintf(text_BOMB_THROWN_SELF, p.Nick))
+		return
+	}
+
+	// Help players that made a typo.
+	if t == nil && suggestion != nil && suggestion != p {
+		g.chat.Public(fmt.Sprintf(text_BOMB_SUGGEST, p.Nick, suggestion.Nick))
+		return
+	}
 
 	// We can't throw to someone who doesn't play.
-	if !playing {
-		g.chat.Public(fmt.Sprintf(text_BOMB_DROPPED, target))
+	if t == nil || !t.active() {
+		g.chat.Public(fmt.Sprintf(text_BOMB_DROPPED, sanitizeNick(target)))
 		g.nextTurn(nil)
 		return
 	}
 
+	// Shields block a single throw, the bomb stays with the thrower.
+	if t.shield {
+		t.shield = false
+		g.turn.Items = append(g.turn.Items, item_SHIELD)
+		g.chat.Public(fmt.Sprintf(text_ITEM_SHIELD_BLOCK, t.Nick, p.Nick))
+		return
+	}
+
 	g.nextTurn(t)
 
 	// Send message.
@@ -390,13 +628,11 @@ func (g *Game) Pickup(nick string) {
 		return
 	}
 
-	nick = sanitizeNick(nick)
-
 	// Attempt to fetch target
-	t, playing := g.Players[nick]
+	t, playing := g.Players[g.key(nick)]
 
 	// Someone who isn't playing can't pick up the bomb.
-	if !playing {
+	if !playing || !t.active() {
 		return
 	}
 
@@ -417,19 +653,15 @@ func (g *Game) Defuse(nick string) {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
-	nick = sanitizeNick(nick)
 	p := g.bomb.location
 
 	// Fast path
-	if g.state != state_PLAYING || p == nil || p.sanitizedNick != nick {
+	if g.state != state_PLAYING || p == nil || p.ID != g.key(nick) || g.defuse != nil {
 		return
 	}
 
-	// TODO: Duplicate code in Defuse and Cut.
-
 	if p.DefuseAttempt {
 		g.chat.Public(fmt.Sprintf(text_DEFUSE_TRIED, p.Nick))
-		g.state = state_PLAYING
 		return
 	}
 
@@ -439,76 +671,59 @@ func (g *Game) Defuse(nick string) {
 		return
 	}
 
+	// Mark this player
+	p.DefuseAttempt = true
+	g.turn.DefuseAttempt = true
+
+	// Lock the bomb to this player.
+	g.defuse = new(defuse)
+	g.defuse.player = p
+	g.defuse.cuts = g.Config.DefuseCuts
+	g.defuse.deadline = time.Now().Add(g.Config.DefuseTimeout)
+
 	// Show defuse info message
-	g.chat.Public(fmt.Sprintf(text_DEFUSE, len(g.bomb.wires)))
+	for _, message := range g.bomb.puzzle.Intro() {
+		g.chat.Public(message)
+	}
+
+	if g.Config.Cooperative {
+		g.sendHints()
+	}
 
 }
 
 // Cut tries to cut a wire during defuse.
 func (g *Game) Cut(nick string, wire uint8) {
+	g.Solve(nick, strconv.Itoa(int(wire)))
+}
+
+// Solve tries to solve the defuse minigame with given answer.
+// This can be a wire to cut, a code or the answer to a question.
+// Only the player that started defusing can answer.
+func (g *Game) Solve(nick, answer string) {
 
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
-	nick = sanitizeNick(nick)
-	p := g.bomb.location
-
 	// Fast path
-	if (g.state != state_PLAYING) || p == nil || p.sanitizedNick != nick || !g.bomb.defusable {
+	if g.state != state_PLAYING || g.defuse == nil || g.defuse.player.ID != g.key(nick) {
 		return
 	}
 
-	// Check if the wire exists
-	if wire < 1 || wire > uint8(len(g.bomb.wires)) {
-		g.chat.Public(fmt.Sprintf(text_DEFUSE_ERROR, wire))
-		g.state = state_PLAYING
-		return
-	}
-	wire = wire - 1
+	outcome, valid := g.bomb.puzzle.Solve(answer)
 
-	if p.DefuseAttempt {
-		g.chat.Public(fmt.Sprintf(text_DEFUSE_TRIED, p.Nick))
-		g.state = state_PLAYING
+	// Check if the answer makes sense, like a wire that exists.
+	if !valid {
+		g.chat.Public(fmt.Sprintf(text_DEFUSE_ERROR, answer))
 		return
 	}
 
-	// Mark this player
-	p.DefuseAttempt = true
-	g.turn.DefuseAttempt = true
-
-	// Check the wire function
-	switch g.bomb.wires[wire] {
-
-	case defuse_SUCCESS:
-		g.bomb.defused = true
-		g.chat.Public(fmt.Sprintf(text_DEFUSE_SUCCESS, p.Nick))
-		g.Stop()
-		return
-
-	case defuse_NOTHING:
-		g.chat.Public(fmt.Sprintf(text_DEFUSE_NOTHING, p.Nick))
-
-	case defuse_LESS_TIME:
-		g.bomb.randomize(20*time.Second, 60*time.Second)
-		g.chat.Public(text_DEFUSE_LESS_TIME)
+	g.defuse.cuts--
+	g.defuse.deadline = time.Now().Add(g.Config.DefuseTimeout)
+	g.defuse.player.Cuts++
+	g.turn.Cuts++
 
-	case defuse_MORE_TIME:
-		d := g.bomb.detonation.Sub(time.Now())
-		g.bomb.randomize(d, d+(5*time.Minute))
-		g.chat.Public(text_DEFUSE_MORE_TIME)
-
-	case defuse_EXPLODE:
-		g.Stop()
-		return
-
-	case defuse_CUT:
-		g.chat.Public(fmt.Sprintf(text_DEFUSE_DUPLICATE, wire))
-
-	}
-
-	g.bomb.wires[wire] = defuse_CUT
-
-	return
+	g.applyDefuse(outcome)
 
 }
 
@@ -518,20 +733,31 @@ func (g *Game) Stop() {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
+	g.end()
+
+}
+
+// end is an internal method and ends the game, the caller should hold the game lock.
+func (g *Game) end() {
+
 	if g.state != state_PLAYING {
 		return
 	}
 
 	// Stop running timers
-	g.stop <- true
+	close(g.stop)
 
 	// The game ended!
 	g.Ended = time.Now()
 	g.state = state_ENDED
 
-	if !g.bomb.defused {
+	// Finalize the last turn, the bomb stays with its holder.
+	if g.turn != nil {
+		g.turn.Duration = g.Ended.Sub(g.turn.Time)
+		g.bomb.location.Duration = g.bomb.location.Duration + g.turn.Duration
+	}
 
-		g.bomb.location.Dead = true
+	if !g.bomb.defused && !g.early {
 
 		// Check if the bomb was lying on the ground at detonation time.
 		if g.bomb.location == nil {
@@ -540,28 +766,17 @@ func (g *Game) Stop() {
 
 		} else {
 
+			g.bomb.location.Dead = true
+
 			// Show message and kick players if we can.
 			if g.bomb.fake {
 				g.chat.Public(text_BOMB_FAKE)
-			} else if !tweak_KICK || !g.chat.IsOperator() {
+			} else if g.bomb.location.Deserted {
 				g.chat.Public(text_BOMB_EXPLODE)
-				g.chat.Public(fmt.Sprintf(text_BOMB_EXPLODE_NOOP, g.bomb.location.Nick))
+				g.chat.Public(fmt.Sprintf(text_BOMB_EXPLODE_DESERTER, g.bomb.location.Nick))
 			} else {
-
-				if tweak_BAN && g.chat.Ban(g.bomb.location.Nick) {
-
-					// Schedule unban!
-					go func() {
-						timer := time.NewTimer(tweak_BAN_TIME * time.Second)
-
-						// Wait for timer to expire
-						<-timer.C
-
-						g.chat.UnBan(g.bomb.location.Nick)
-					}()
-				}
-
-				g.chat.Kick(g.bomb.location.Nick, text_BOMB_EXPLODE)
+				g.punish(g.bomb.location)
+				g.bomb.kind.Explode(g)
 			}
 
 		}
@@ -571,7 +786,16 @@ func (g *Game) Stop() {
 	// Calculate statistics
 	for _, p := range g.Players {
 		p.Turns = len(p.turns)
-		p.MeanDuration = p.Duration / time.Duration(p.Turns)
+		if p.Turns > 0 {
+			p.MeanDuration = p.Duration / time.Duration(p.Turns)
+		}
+		for _, t := range p.turns {
+			if t.Dropped {
+				p.Drops++
+			} else if t.target != nil && !t.Forced {
+				p.Throws++
+			}
+		}
 	}
 
 	if g.Scorer == nil {
@@ -579,43 +803,131 @@ func (g *Game) Stop() {
 	}
 
 	for _, p := range g.Players {
-		g.Scores = append(g.Scores, g.Scorer(g, p))
+		c := g.Scorer(g, p)
+		g.handicap(c)
+		g.Scores = append(g.Scores, c)
 	}
 
 	sort.Sort(g.Scores)
 
+	// Remember the results for the next game.
+	g.lastWinner = g.Scores[0].Player.ID
+	g.lastLoser = g.Scores[len(g.Scores)-1].Player.ID
+	if v := g.victim(); v != nil {
+		g.lastLoser = v.ID
+	}
+
 	g.chat.Public(fmt.Sprintf(text_END_WINNER, g.Scores[0].Player.Nick))
 
+	g.settleBets()
+	g.updateRecords()
+
 	// Ready for restart!
 	g.state = state_INIT
 
 }
 
+// victim returns the player the bomb exploded on, or nil if nobody exploded.
+func (g *Game) victim() *Player {
+
+	if g.bomb.defused || g.bomb.fake || g.early {
+		return nil
+	}
+
+	return g.bomb.location
+}
+
 // Leave removes a player after he has left the room.
+// Players leaving a running game stay on the scoreboard as deserters.
 func (g *Game) Leave(nick string) {
 
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
-	// TODO: Quit game if we don't have enough players anymore.
+	key := g.key(nick)
+
+	// Someone else might take this nickname, forget who was behind it.
+	delete(g.ids, sanitizeNick(nick))
 
 	if g.state == state_INIT || g.state == state_ENDED {
 		return
 	}
 
-	nick = sanitizeNick(nick)
-
 	// Attempt to fetch the player
-	p, playing := g.Players[nick]
+	p, playing := g.Players[key]
 
-	// This one isn't playing, couldn't care less.
-	if !playing {
+	// This one isn't playing, couldn't care less. Players that were killed
+	// already left the game.
+	if !playing || !p.active() {
 		return
 	}
 
 	g.chat.Public(fmt.Sprintf(text_PLAYER_LEFT, p.Nick))
 
-	delete(g.Players, nick)
+	g.leave(p)
+
+}
+
+// leave is an internal method and removes a player from the game, the caller
+// should hold the game lock.
+func (g *Game) leave(p *Player) {
+
+	// Nothing to lose before the game started.
+	if g.state == state_WARMUP {
+		delete(g.Players, p.ID)
+		if g.first == p {
+			g.first = g.randomPlayer(nil)
+			g.announced = false
+		}
+		return
+	}
+
+	p.Deserted = true
+	p.left = time.Now()
+
+	// Deal with the bomb if the deserter was holding it.
+	if g.bomb.location == p {
+		if g.Config.DeserterExplode {
+			g.end()
+			return
+		}
+		g.chat.Public(fmt.Sprintf(text_BOMB_DROPPED_DESERTER, p.Nick))
+		g.nextTurn(nil)
+	}
+
+	// Check if we have enough players to continue.
+	g.checkPlayers()
+
+}
+
+// activePlayers returns the number of players still in the game.
+func (g *Game) activePlayers() (n int) {
+	for _, p := range g.Players {
+		if p.active() {
+			n++
+		}
+	}
+	return
+}
+
+// abort is an internal method and stops the game without results, the
+// caller should hold the game lock.
+func (g *Game) abort() {
+
+	if g.state != state_PLAYING && g.state != state_WARMUP {
+		return
+	}
+
+	// Stop running timers
+	close(g.stop)
+
+	g.Ended = time.Now()
+	g.refundBets("")
+
+	g.chat.Public(text_END_ABORTED)
+
+	// Ready for restart!
+	g.state = state_INIT
 
 }
 
@@ -625,14 +937,20 @@ func (g *Game) Rename(old, nick string) {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
+	key := g.key(old)
+
+	// Keep the identity of this player.
+	if id, known := g.ids[sanitizeNick(old)]; known {
+		delete(g.ids, sanitizeNick(old))
+		g.ids[sanitizeNick(nick)] = id
+	}
+
 	if g.state == state_INIT || g.state == state_ENDED {
 		return
 	}
 
-	olds := sanitizeNick(old)
-
 	// Attempt to fetch the player
-	p, playing := g.Players[olds]
+	p, playing := g.Players[key]
 
 	// This one isn't playing, couldn't care less.
 	if !playing {
@@ -642,8 +960,12 @@ func (g *Game) Rename(old, nick string) {
 	p.Nick = nick
 	p.sanitizedNick = sanitizeNick(nick)
 
-	delete(g.Players, olds)
-	g.Players[p.sanitizedNick] = p
+	// Players without a stable identity are known by their nickname.
+	if p.nickID {
+		delete(g.Players, key)
+		p.ID = p.sanitizedNick
+		g.Players[p.ID] = p
+	}
 
 	g.chat.Public(fmt.Sprintf(text_PLAYER_RENAME, old, p.Nick))
 
@@ -659,28 +981,52 @@ func (g *Game) PlayerList() {
 		return
 	}
 
-	players := make([]string, len(g.Players))
+	players := make([]string, 0, len(g.Players))
 
-	i := 0
 	for _, player := range g.Players {
-		players[i] = player.Nick
-		i++
+		if player.active() {
+			players = append(players, player.Nick)
+		}
 	}
 
 	g.chat.Public(fmt.Sprintf(text_PLAYER_LIST, strings.Join(players, ", ")))
 
 }
 
+// commands lists the commands Decode handles, others don't count towards
+// the rate limits.
+var commands = map[string]bool{
+	cmd_JOIN: true, cmd_PASS: true, cmd_DEFUSE: true, cmd_CUT: true, cmd_CODE: true,
+	cmd_ANSWER: true, cmd_USE: true, cmd_ITEMS: true, cmd_BET: true, cmd_BALANCE: true,
+	cmd_RATING: true, cmd_PLAYER_LIST: true, cmd_PICK_UP: true, cmd_OPT_OUT: true,
+	cmd_OPT_IN: true, cmd_ADMIN: true,
+}
+
 // Decode attempts to find game commands in given message.
 // This provides access to everything except starting the game.
 func (g *Game) Decode(sender, message string) {
 
-	if len(message) < 3 || !strings.HasPrefix(message, cmd_PREFIX) {
+	// Nothing to answer with.
+	if g.chat == nil {
+		return
+	}
+
+	// Other messages might be players sharing hints.
+	if !strings.HasPrefix(message, cmd_PREFIX) {
+		g.hear(sender, message)
+		return
+	}
+
+	if len(message) < 3 {
 		return
 	}
 
 	args := strings.Split(message[1:], " ")
 
+	if !commands[args[0]] || !g.allow(sender) {
+		return
+	}
+
 	switch args[0] {
 
 	case cmd_JOIN:
@@ -694,19 +1040,53 @@ func (g *Game) Decode(sender, message string) {
 	case cmd_DEFUSE:
 		g.Defuse(sender)
 
-	case cmd_CUT:
+	case cmd_CUT, cmd_CODE, cmd_ANSWER:
 		if len(args) > 1 {
-			if n, err := strconv.ParseUint(args[1], 10, 8); err == nil {
-				g.Cut(sender, uint8(n))
-			}
+			g.Solve(sender, strings.Join(args[1:], " "))
+		}
+
+	case cmd_USE:
+		if len(args) > 2 {
+			g.Use(sender, args[1], args[2])
+		} else if len(args) > 1 {
+			g.Use(sender, args[1], "")
+		}
+
+	case cmd_ITEMS:
+		g.Inventory(sender)
+
+	case cmd_BET:
+		if len(args) > 3 {
+			g.PlaceBet(sender, args[1], args[2], args[3])
+		} else if len(args) > 2 {
+			g.PlaceBet(sender, args[1], args[2], "")
+		}
+
+	case cmd_BALANCE:
+		g.Balance(sender)
+
+	case cmd_RATING:
+		if len(args) > 1 {
+			g.Rating(sender, args[1])
+		} else {
+			g.Rating(sender, "")
 		}
 
 	case cmd_PLAYER_LIST:
-		go g.PlayerList()
+		g.PlayerList()
 
 	case cmd_PICK_UP:
 		g.Pickup(sender)
 
+	case cmd_OPT_OUT:
+		g.OptOut(sender, true)
+
+	case cmd_OPT_IN:
+		g.OptOut(sender, false)
+
+	case cmd_ADMIN:
+		g.admin(sender, args[1:])
+
 	}
 
 }
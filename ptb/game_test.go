@@ -0,0 +1,84 @@
+package ptb
+
+import (
+	"sync"
+	"testing"
+)
+
+// fakeChat records the messages sent by the game.
+type fakeChat struct {
+	mutex   sync.Mutex
+	public  []string
+	private map[string][]string
+}
+
+func (c *fakeChat) Public(message string) {
+	c.mutex.Lock()
+	c.public = append(c.public, message)
+	c.mutex.Unlock()
+}
+
+func (c *fakeChat) Private(nick, message string) {
+	c.mutex.Lock()
+	if c.private == nil {
+		c.private = make(map[string][]string)
+	}
+	c.private[nick] = append(c.private[nick], message)
+	c.mutex.Unlock()
+}
+
+func (c *fakeChat) Kick(nick, reason string) {}
+func (c *fakeChat) IsOperator() bool         { return true }
+func (c *fakeChat) Ban(nick string) bool     { return true }
+func (c *fakeChat) UnBan(nick string)        {}
+
+// sent returns the private messages sent to given nickname.
+func (c *fakeChat) sent(nick string) []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.private[nick]
+}
+
+// newTestGame returns a game using a fake chat.
+func newTestGame() (*Game, *fakeChat) {
+	c := new(fakeChat)
+	return NewGame(c), c
+}
+
+// player returns a player as added by Join, with given nickname and ID.
+// An empty ID makes the sanitized nickname the ID.
+func player(nick, id string) *Player {
+
+	p := &Player{Nick: nick, ID: id, sanitizedNick: sanitizeNick(nick)}
+
+	if id == "" {
+		p.ID = p.sanitizedNick
+		p.nickID = true
+	}
+
+	return p
+}
+
+func TestSanitizeNick(t *testing.T) {
+
+	tests := []struct {
+		nick string
+		out  string
+	}{
+		{"Bob", "bob"},
+		{"  Bob\t", "bob"},
+		{"B\u200bob", "bob"},
+		{"\u200dBob\u200d", "bob"},
+		{"\uff22\uff4f\uff42", "bob"},
+		{"Bob\u3000", "bob"},
+		{"Bob_2", "bob_2"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if out := sanitizeNick(test.nick); out != test.out {
+			t.Errorf("%q: got %q, want %q", test.nick, out, test.out)
+		}
+	}
+
+}
@@ -0,0 +1,78 @@
+package ptb
+
+import (
+	"time"
+)
+
+// First holder selection
+const (
+	FirstJoined      = iota // Player that joined first.
+	FirstRandom             // Random player.
+	FirstLowestRated        // Player with the lowest rating.
+	FirstWinner             // Winner of the last game.
+	FirstLoser              // Loser of the last game.
+)
+
+// Handicaps
+const (
+	tweak_FIRST_HOLDER   = FirstJoined // Strategy to choose who gets the bomb first.
+	tweak_HANDICAP_HOLD  = 0           // Seconds taken off the maximum hold time per consecutive win.
+	tweak_HANDICAP_SCORE = 0           // Score reduction in percent per consecutive win.
+)
+
+// chooseFirst returns the player that gets the bomb first.
+// Falls back to the first player that joined.
+func (g *Game) chooseFirst() *Player {
+
+	var p *Player
+
+	switch g.Config.FirstHolder {
+	case FirstRandom:
+		p = g.randomPlayer(nil)
+	case FirstLowestRated:
+		p = g.lowestRated()
+	case FirstWinner:
+		p = g.Players[g.lastWinner]
+	case FirstLoser:
+		p = g.Players[g.lastLoser]
+	}
+
+	if p == nil {
+		return g.first
+	}
+
+	return p
+}
+
+// streak returns the number of consecutive wins for given player.
+func (g *Game) streak(p *Player) int {
+	return g.record(p).Streak
+}
+
+// maxHold returns the maximum hold time for given player, shortened for
+// players on a winning streak, down to half the normal time.
+func (g *Game) maxHold(p *Player) time.Duration {
+
+	d := g.Config.MaxHold - g.Config.HandicapHold*time.Duration(g.streak(p))
+
+	if d < g.Config.MaxHold/2 {
+		return g.Config.MaxHold / 2
+	}
+
+	return d
+}
+
+// handicap reduces the score of players on a winning streak, by at most half.
+func (g *Game) handicap(c *ScoreCard) {
+
+	percent := g.Config.HandicapScore * g.streak(c.Player)
+
+	if percent <= 0 {
+		return
+	} else if percent > 50 {
+		percent = 50
+	}
+
+	c.Player.Handicap = percent
+	c.Score = c.Score * uint64(100-percent) / 100
+}
@@ -0,0 +1,69 @@
+package ptb
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// checkHold takes the bomb away from a player that held it for too long.
+// Players are warned privately before this happens.
+func (g *Game) checkHold() {
+
+	p := g.bomb.location
+
+	// Fast path, nobody is holding the bomb, the holder is defusing or there's no limit.
+	if g.Config.MaxHold <= 0 || p == nil || g.turn == nil || g.defuse != nil {
+		return
+	}
+
+	held := time.Now().Sub(g.turn.Time)
+	max := g.maxHold(p)
+
+	if held < max {
+
+		// Warn the holder once the limit comes close.
+		if !g.turn.warned && held >= max-g.Config.HoldWarning {
+			g.turn.warned = true
+			remaining := (max - held) / time.Second
+			g.chat.Private(p.Nick, fmt.Sprintf(text_HOLD_WARNING, remaining))
+		}
+
+		return
+	}
+
+	g.turn.Forced = true
+
+	target := g.randomPlayer(p)
+
+	if g.Config.MaxHoldDrop || target == nil {
+		g.chat.Public(fmt.Sprintf(text_HOLD_DROPPED, p.Nick))
+		g.nextTurn(nil)
+		return
+	}
+
+	g.nextTurn(target)
+
+	g.chat.Public(fmt.Sprintf(text_HOLD_PASSED, p.Nick, target.Nick))
+
+}
+
+// randomPlayer returns a random player other than the given one, or nil if
+// there is nobody else.
+func (g *Game) randomPlayer(exclude *Player) *Player {
+
+	players := make([]*Player, 0, len(g.Players))
+
+	for _, p := range g.Players {
+		if p != exclude && p.active() {
+			players = append(players, p)
+		}
+	}
+
+	if len(players) == 0 {
+		return nil
+	}
+
+	return players[rand.Intn(len(players))]
+
+}
@@ -0,0 +1,102 @@
+package ptb
+
+// Identifier can be implemented by a Chat to identify players by something
+// more stable than their nickname, like an account name.
+// The game asks while holding its lock, so Identity shouldn't wait for the
+// network.
+type Identifier interface {
+	Identity(nick string) string // Returns the identity for given nickname, or an empty string if unknown.
+}
+
+// identity returns the identity of given nickname, if the chat supports it.
+func (g *Game) identity(nick string) string {
+
+	if g.chat == nil {
+		return ""
+	}
+
+	if i, ok := g.chat.Chat.(Identifier); ok {
+		return i.Identity(nick)
+	}
+
+	return ""
+}
+
+// key returns the key used to find the player with given nickname.
+// This is the identity passed to Identify or provided by the chat, or the
+// sanitized nickname if we don't know who's behind it.
+func (g *Game) key(nick string) string {
+	key, _ := g.lookup(nick)
+	return key
+}
+
+// lookup returns the key for given nickname, and true if it's a stable identity.
+func (g *Game) lookup(nick string) (string, bool) {
+
+	s := sanitizeNick(nick)
+
+	if id, known := g.ids[s]; known {
+		return id, true
+	}
+
+	if id := g.identity(nick); id != "" {
+		return id, true
+	}
+
+	return s, false
+}
+
+// byNick returns the player currently using given nickname, or nil.
+// Use this for nicknames typed by players, like throw targets.
+func (g *Game) byNick(nick string) *Player {
+
+	s := sanitizeNick(nick)
+
+	for _, p := range g.Players {
+		if p.sanitizedNick == s {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// Identify tells the game who is behind a nickname.
+// IDs should be stable and unique, like an account name or user ID, so
+// players keep their stats when changing nicknames.
+func (g *Game) Identify(nick, id string) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.ids == nil {
+		g.ids = make(map[string]string)
+	}
+
+	if id == "" {
+		delete(g.ids, sanitizeNick(nick))
+		return
+	}
+
+	g.ids[sanitizeNick(nick)] = id
+
+}
+
+// JoinUser adds a player with a stable ID to the game.
+func (g *Game) JoinUser(id, nick string) {
+	g.Identify(nick, id)
+	g.Join(nick)
+}
+
+// ThrowUser sends the bomb to another player, for a source with a stable ID.
+func (g *Game) ThrowUser(id, source, target string) {
+	g.Identify(source, id)
+	g.Throw(source, target)
+}
+
+// DecodeUser attempts to find game commands in a message from a sender with
+// a stable ID.
+func (g *Game) DecodeUser(id, sender, message string) {
+	g.Identify(sender, id)
+	g.Decode(sender, message)
+}
@@ -0,0 +1,160 @@
+package ptb
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Items players can use.
+const (
+	item_SHIELD  = "shield"  // Blocks the next throw to this player.
+	item_PEEK    = "peek"    // Shows a hint about the time left.
+	item_REVERSE = "reverse" // Throws the bomb back to the previous holder.
+	item_FREEZE  = "freeze"  // Stops another player from throwing for a while.
+)
+
+// itemNames lists all items that can be handed out.
+var itemNames = []string{item_SHIELD, item_PEEK, item_REVERSE, item_FREEZE}
+
+// giveItem randomly adds an item to the player's inventory.
+func (g *Game) giveItem(p *Player) {
+
+	if !g.Config.Items || rand.Intn(100) >= g.Config.ItemChance {
+		return
+	}
+
+	item := itemNames[rand.Intn(len(itemNames))]
+	p.Items[item]++
+
+	g.chat.Private(p.Nick, fmt.Sprintf(text_ITEM_RECEIVED, item))
+
+}
+
+// Use uses an item from the player's inventory.
+// Some items need a target player.
+func (g *Game) Use(nick, item, target string) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.state != state_PLAYING || !g.Config.Items {
+		return
+	}
+
+	p, playing := g.Players[g.key(nick)]
+
+	// Fast path
+	if !playing || !p.active() {
+		return
+	}
+
+	item = strings.ToLower(item)
+
+	if p.Items[item] <= 0 {
+		g.chat.Private(p.Nick, fmt.Sprintf(text_ITEM_MISSING, item))
+		return
+	}
+
+	switch item {
+
+	case item_SHIELD:
+		if p.shield {
+			g.chat.Private(p.Nick, text_ITEM_SHIELD_ACTIVE)
+			return
+		}
+		p.shield = true
+		g.chat.Public(fmt.Sprintf(text_ITEM_SHIELD, p.Nick))
+
+	case item_PEEK:
+		// Give a range of a few minutes around the actual time.
+		remaining := g.bomb.detonation.Sub(time.Now())
+		low := (remaining - time.Duration(rand.Int63n(int64(2*time.Minute)))) / time.Minute
+		if low < 0 {
+			low = 0
+		}
+		g.chat.Private(p.Nick, fmt.Sprintf(text_ITEM_PEEK, low, low+3))
+
+	case item_REVERSE:
+		if g.bomb.location != p || g.turn == nil || g.turn.source == nil || !g.turn.source.active() || g.defuse != nil {
+			g.chat.Private(p.Nick, text_ITEM_REVERSE_FAIL)
+			return
+		}
+		// Sending the bomb back is a throw, the same rules apply.
+		if time.Now().Before(p.frozen) {
+			g.chat.Public(fmt.Sprintf(text_ITEM_FROZEN, p.Nick))
+			return
+		}
+		if !g.bomb.kind.CanThrow(g) {
+			g.chat.Public(fmt.Sprintf(text_BOMB_STUCK, p.Nick))
+			return
+		}
+		t := g.turn.source
+		g.turn.Items = append(g.turn.Items, item)
+		if t.shield {
+			t.shield = false
+			g.turn.Items = append(g.turn.Items, item_SHIELD)
+			g.chat.Public(fmt.Sprintf(text_ITEM_SHIELD_BLOCK, t.Nick, p.Nick))
+			break
+		}
+		g.nextTurn(t)
+		g.chat.Public(fmt.Sprintf(text_ITEM_REVERSE, p.Nick, t.Nick))
+
+	case item_FREEZE:
+		t, _ := g.resolve(target)
+		if t == nil || t == p || !t.active() {
+			g.chat.Private(p.Nick, fmt.Sprintf(text_ITEM_TARGET, item))
+			return
+		}
+		t.frozen = time.Now().Add(g.Config.FreezeTime)
+		g.chat.Public(fmt.Sprintf(text_ITEM_FREEZE, p.Nick, t.Nick))
+
+	default:
+		return
+
+	}
+
+	p.Items[item]--
+
+	// Keep track of items used during this turn, by anyone.
+	if g.turn != nil && item != item_REVERSE {
+		g.turn.Items = append(g.turn.Items, item)
+	}
+
+}
+
+// Inventory privately shows the items a player has.
+func (g *Game) Inventory(nick string) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.state != state_PLAYING || !g.Config.Items {
+		return
+	}
+
+	p, playing := g.Players[g.key(nick)]
+
+	if !playing {
+		return
+	}
+
+	items := make([]string, 0, len(p.Items))
+	for item, n := range p.Items {
+		if n > 0 {
+			items = append(items, fmt.Sprintf("%s (%d)", item, n))
+		}
+	}
+
+	if len(items) == 0 {
+		g.chat.Private(p.Nick, text_ITEM_NONE)
+		return
+	}
+
+	sort.Strings(items)
+
+	g.chat.Private(p.Nick, fmt.Sprintf(text_ITEM_LIST, strings.Join(items, ", ")))
+
+}
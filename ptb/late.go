@@ -0,0 +1,65 @@
+package ptb
+
+import (
+	"time"
+)
+
+// Late join policies
+const (
+	LateAllow   = iota // Players can join at any time.
+	LateNever          // Players can only join before the game starts.
+	LateWindow         // Players can join during the first seconds of the game.
+	LateDropped        // Players can only join while the bomb is on the ground.
+)
+
+// Late joins
+const (
+	tweak_LATE_JOIN    = LateAllow // Policy for players joining after the game started.
+	tweak_LATE_WINDOW  = 60        // Time players can join after the game started in seconds.
+	tweak_LATE_PENALTY = 0         // Score reduction in percent for late joiners.
+)
+
+// Deserters
+const (
+	tweak_DESERTER_EXPLODE = false // Explode the bomb when the holder leaves, instead of dropping it.
+	tweak_DESERTER_PENALTY = 100   // Score reduction in percent for players that left during the game.
+	tweak_ABORT_ON_LEAVE   = false // Abort the game instead of ending it early when too few players are left.
+)
+
+// lateAllowed returns true if players can join the game right now.
+func (g *Game) lateAllowed() bool {
+
+	switch g.Config.LateJoin {
+	case LateNever:
+		return false
+	case LateWindow:
+		return time.Now().Sub(g.playing) < g.Config.LateWindow
+	case LateDropped:
+		return g.bomb.location == nil
+	}
+
+	return true
+}
+
+// penalize reduces the score of a player that joined late or deserted.
+func penalize(g *Game, c *ScoreCard) {
+
+	percent := 0
+
+	if c.Player.Late {
+		percent = percent + g.Config.LatePenalty
+	}
+
+	if c.Player.Deserted {
+		percent = percent + g.Config.DeserterPenalty
+	}
+
+	if percent <= 0 {
+		return
+	} else if percent >= 100 {
+		c.Score = 0
+		return
+	}
+
+	c.Score = c.Score * uint64(100-percent) / 100
+}
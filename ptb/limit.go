@@ -0,0 +1,117 @@
+package ptb
+
+import (
+	"time"
+)
+
+// Rate limits
+const (
+	tweak_COMMAND_BURST     = 5    // Commands a player can send in a row.
+	tweak_COMMAND_INTERVAL  = 2    // Time to earn another command in seconds.
+	tweak_ROOM_BURST        = 20   // Commands all players together can send in a row.
+	tweak_ROOM_INTERVAL     = 1    // Time to earn another command for the room in seconds.
+	tweak_LIMIT_WARNING     = true // Warn players privately when their commands are ignored.
+	tweak_OUTBOUND_BURST    = 5    // Messages the bot can send in a row.
+	tweak_OUTBOUND_INTERVAL = 1    // Time to earn another message in seconds.
+)
+
+// bucket limits how often something can happen: it holds up to burst
+// tokens, and earns a new one every interval.
+type bucket struct {
+	tokens int
+	last   time.Time
+	warned bool
+}
+
+// refill adds the tokens earned since the last refill.
+func (b *bucket) refill(burst int, interval time.Duration, now time.Time) {
+
+	if b.last.IsZero() {
+		b.tokens = burst
+		b.last = now
+		return
+	}
+
+	n := int(now.Sub(b.last) / interval)
+	b.last = b.last.Add(time.Duration(n) * interval)
+	b.tokens += n
+
+	if b.tokens >= burst {
+		b.tokens = burst
+		b.last = now
+	}
+
+}
+
+// take uses a token, returns false if there are none left.
+// Limits are disabled if burst or interval is zero.
+func (b *bucket) take(burst int, interval time.Duration) bool {
+
+	if burst <= 0 || interval <= 0 {
+		return true
+	}
+
+	b.refill(burst, interval, time.Now())
+
+	if b.tokens <= 0 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// ready returns true if a token can be taken, without using it.
+func (b *bucket) ready(burst int, interval time.Duration) bool {
+
+	if burst <= 0 || interval <= 0 {
+		return true
+	}
+
+	b.refill(burst, interval, time.Now())
+
+	return b.tokens > 0
+}
+
+// wait returns the time until the next token is earned.
+func (b *bucket) wait(interval time.Duration) time.Duration {
+	return b.last.Add(interval).Sub(time.Now())
+}
+
+// allow returns true if the game should handle a command from given nickname.
+// Players going over their limit are warned once.
+func (g *Game) allow(nick string) bool {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.limits == nil {
+		g.limits = make(map[string]*bucket)
+	}
+
+	key := g.key(nick)
+
+	b, known := g.limits[key]
+	if !known {
+		b = new(bucket)
+		g.limits[key] = b
+	}
+
+	// Only use tokens if both limits allow it, commands ignored for the room
+	// don't count for the player.
+	if b.ready(g.Config.CommandBurst, g.Config.CommandInterval) &&
+		g.room.ready(g.Config.RoomBurst, g.Config.RoomInterval) {
+		b.take(g.Config.CommandBurst, g.Config.CommandInterval)
+		g.room.take(g.Config.RoomBurst, g.Config.RoomInterval)
+		b.warned = false
+		return true
+	}
+
+	if g.Config.LimitWarning && !b.warned {
+		b.warned = true
+		g.chat.Private(nick, text_LIMITED)
+	}
+
+	return false
+}
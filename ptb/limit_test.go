@@ -0,0 +1,53 @@
+package ptb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		player    int // Player burst.
+		room      int // Room burst.
+		commands  []string
+		allowed   []bool
+		warned    int // Warnings sent to bob.
+		bobTokens int // Tokens bob has left.
+	}{
+		{"player limit", 2, 10, []string{"bob", "bob", "bob", "bob"}, []bool{true, true, false, false}, 1, 0},
+		{"room limit", 10, 2, []string{"bob", "alice", "bob"}, []bool{true, true, false}, 1, 9},
+		{"other players", 1, 10, []string{"bob", "alice", "bob", "alice"}, []bool{true, true, false, false}, 1, 0},
+		{"disabled", 0, 0, []string{"bob", "bob", "bob"}, []bool{true, true, true}, 0, 0},
+	}
+
+	for _, test := range tests {
+
+		g, chat := newTestGame()
+		g.Config.CommandBurst = test.player
+		g.Config.CommandInterval = time.Hour
+		g.Config.RoomBurst = test.room
+		g.Config.RoomInterval = time.Hour
+		g.Config.OutboundBurst = 0
+
+		for i, nick := range test.commands {
+			if g.allow(nick) != test.allowed[i] {
+				t.Errorf("%s: command %d should be allowed: %v", test.name, i, test.allowed[i])
+			}
+		}
+
+		g.chat.Flush()
+
+		if n := len(chat.sent("bob")); n != test.warned {
+			t.Errorf("%s: bob was warned %d times, want %d", test.name, n, test.warned)
+		}
+
+		// Commands ignored for the room don't use the player's tokens.
+		if b := g.limits["bob"]; b.tokens != test.bobTokens {
+			t.Errorf("%s: bob has %d tokens, want %d", test.name, b.tokens, test.bobTokens)
+		}
+
+	}
+
+}
@@ -0,0 +1,126 @@
+package ptb
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Target matching
+const (
+	MatchStrict = iota // Nicknames have to match exactly, apart from case.
+	MatchLoose         // Punctuation is ignored.
+	MatchPrefix        // Punctuation is ignored and a unique prefix is enough.
+)
+
+// Target matching
+const (
+	tweak_TARGET_MATCH = MatchStrict // How throw targets are matched to players.
+	tweak_SUGGEST_DIST = 2           // Maximum edit distance for "did you mean" suggestions.
+)
+
+// looseNick returns the nickname with only letters and digits.
+func looseNick(nick string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, nick)
+}
+
+// resolve finds the player meant by a nickname typed by another player.
+// If nobody matches, a player with a similar nickname might be suggested.
+// Nicknames that match several players don't resolve to anyone.
+func (g *Game) resolve(nick string) (p *Player, suggestion *Player) {
+
+	if p = g.byNick(nick); p != nil || g.Config.TargetMatch == MatchStrict {
+		return
+	}
+
+	loose := looseNick(nick)
+	if loose == "" {
+		return
+	}
+
+	var exact, prefixed *Player
+	exacts, prefixes := 0, 0
+	best := tweak_SUGGEST_DIST + 1
+
+	for _, c := range g.Players {
+
+		if !c.active() {
+			continue
+		}
+
+		n := looseNick(c.Nick)
+
+		if n == loose {
+			exact = c
+			exacts++
+		}
+
+		if strings.HasPrefix(n, loose) {
+			prefixed = c
+			prefixes++
+		}
+
+		// Keep the closest nickname, but only if there's a single one.
+		if d := distance(n, loose); d < best {
+			best = d
+			suggestion = c
+		} else if d == best {
+			suggestion = nil
+		}
+	}
+
+	switch {
+	case exacts == 1:
+		return exact, nil
+	case exacts > 1:
+		// Can't tell them apart, don't suggest one of them either.
+		return nil, nil
+	}
+
+	if g.Config.TargetMatch == MatchPrefix && prefixes == 1 {
+		return prefixed, nil
+	}
+
+	return nil, suggestion
+}
+
+// distance returns the Levenshtein distance between two strings.
+func distance(a, b string) int {
+
+	s, t := []rune(a), []rune(b)
+
+	row := make([]int, len(t)+1)
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+
+		prev := row[0]
+		row[0] = i
+
+		for j := 1; j <= len(t); j++ {
+
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+
+			next := prev + cost
+			if row[j]+1 < next {
+				next = row[j] + 1
+			}
+			if row[j-1]+1 < next {
+				next = row[j-1] + 1
+			}
+
+			prev, row[j] = row[j], next
+		}
+	}
+
+	return row[len(t)]
+}
@@ -0,0 +1,109 @@
+package ptb
+
+import (
+	"testing"
+)
+
+func TestLooseNick(t *testing.T) {
+
+	tests := []struct {
+		nick string
+		out  string
+	}{
+		{"Bob", "bob"},
+		{"[Bob]", "bob"},
+		{"b.o-b_", "bob"},
+		{"Bob2", "bob2"},
+		{"Zoë", "zoë"},
+		{"__", ""},
+	}
+
+	for _, test := range tests {
+		if out := looseNick(test.nick); out != test.out {
+			t.Errorf("%q: got %q, want %q", test.nick, out, test.out)
+		}
+	}
+
+}
+
+func TestDistance(t *testing.T) {
+
+	tests := []struct {
+		a, b string
+		d    int
+	}{
+		{"", "", 0},
+		{"bob", "bob", 0},
+		{"bob", "", 3},
+		{"", "bob", 3},
+		{"bob", "rob", 1},
+		{"bob", "bobby", 2},
+		{"alice", "alcie", 2},
+		{"zoë", "zoe", 1},
+	}
+
+	for _, test := range tests {
+		if d := distance(test.a, test.b); d != test.d {
+			t.Errorf("%q %q: got %d, want %d", test.a, test.b, d, test.d)
+		}
+	}
+
+}
+
+func TestResolve(t *testing.T) {
+
+	g, _ := newTestGame()
+
+	dead := player("Dave", "")
+	dead.Dead = true
+
+	g.Players = make(map[string]*Player)
+	for _, p := range []*Player{player("Bob", ""), player("[Alice]", ""), player("Carol", ""), player("carol_", "@carol:x"), player("Bobby", ""), dead} {
+		g.Players[p.ID] = p
+	}
+
+	tests := []struct {
+		match      int
+		nick       string
+		player     string
+		suggestion string
+	}{
+		{MatchStrict, "bob", "Bob", ""},
+		{MatchStrict, "alice", "", ""},
+		{MatchLoose, "alice", "[Alice]", ""},
+		{MatchLoose, "alcie", "", "[Alice]"},
+		{MatchLoose, "ali", "", "[Alice]"},
+		{MatchPrefix, "ali", "[Alice]", ""},
+		// Prefixes of several players.
+		{MatchPrefix, "bo", "", "Bob"},
+		// Exact matches win over prefixes.
+		{MatchPrefix, "bob!", "Bob", ""},
+		// Loosely matching several players is ambiguous.
+		{MatchLoose, "carol.", "", ""},
+		{MatchPrefix, "car", "", ""},
+		// Dead players can't be targets.
+		{MatchLoose, "dave.", "", ""},
+		{MatchLoose, "...", "", ""},
+	}
+
+	for _, test := range tests {
+
+		g.Config.TargetMatch = test.match
+
+		p, suggestion := g.resolve(test.nick)
+
+		if nick(p) != test.player || nick(suggestion) != test.suggestion {
+			t.Errorf("%d %q: got %q %q, want %q %q", test.match, test.nick, nick(p), nick(suggestion), test.player, test.suggestion)
+		}
+
+	}
+
+}
+
+// nick returns the nickname of a player, or an empty string for nil.
+func nick(p *Player) string {
+	if p == nil {
+		return ""
+	}
+	return p.Nick
+}
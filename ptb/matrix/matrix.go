@@ -0,0 +1,348 @@
+// Package matrix connects Pass The Bomb to a Matrix room using the
+// client-server API.
+//
+//	c := matrix.NewClient("https://matrix.org", token, "!room:matrix.org")
+//	g := ptb.NewGame(c)
+//	err := c.Run(g)
+package matrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API paths
+const (
+	path_PREFIX = "/_matrix/client/v3"
+	path_WHOAMI = "/account/whoami"
+	path_SYNC   = "/sync"
+	path_CREATE = "/createRoom"
+	path_ROOM   = "/rooms/"
+)
+
+// Client is a Chat for a single Matrix room.
+// Players are known by their display name, and identified by their user ID.
+type Client struct {
+	BaseURL string       // Homeserver URL, like https://matrix.org.
+	Token   string       // Access token of the bot account.
+	Room    string       // Room ID, like !abc:matrix.org.
+	HTTP    *http.Client // Client used for requests.
+
+	UserID string // User ID of the bot, set by Run if empty.
+
+	mutex   sync.Mutex
+	txn     int64
+	members map[string]string // User IDs for display names.
+	names   map[string]string // Display names for user IDs.
+	direct  map[string]string // Direct message rooms for user IDs.
+	banned  map[string]string // User IDs for banned display names.
+}
+
+// NewClient returns a client for given room.
+func NewClient(baseURL, token, room string) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		Room:    room,
+		HTTP:    http.DefaultClient,
+		txn:     time.Now().UnixNano(),
+		members: make(map[string]string),
+		names:   make(map[string]string),
+		direct:  make(map[string]string),
+		banned:  make(map[string]string),
+	}
+}
+
+// Error is returned by the homeserver when a request fails.
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"errcode"`
+	Message string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("matrix: %s: %s (%d)", e.Code, e.Message, e.Status)
+}
+
+// do sends a request to the homeserver and decodes the response into out,
+// if not nil.
+func (c *Client) do(method, path string, query url.Values, in, out interface{}) error {
+
+	u := c.BaseURL + path_PREFIX + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		e := &Error{Status: resp.StatusCode}
+		json.Unmarshal(b, e)
+		return e
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal(b, out)
+}
+
+// room returns the path for an endpoint in given room.
+func room(id string, endpoint ...string) string {
+	return path_ROOM + url.PathEscape(id) + "/" + strings.Join(endpoint, "/")
+}
+
+// txnID returns a new transaction ID for sending events.
+func (c *Client) txnID() string {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.txn++
+
+	return strconv.FormatInt(c.txn, 36)
+}
+
+// userID returns the user ID for given display name.
+// User IDs are accepted as well.
+func (c *Client) userID(nick string) string {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if id, known := c.members[nick]; known {
+		return id
+	}
+
+	if id, known := c.banned[nick]; known {
+		return id
+	}
+
+	return nick
+}
+
+// send sends a text message to given room.
+func (c *Client) send(roomID, message string) error {
+
+	content := map[string]string{
+		"msgtype": "m.text",
+		"body":    message,
+	}
+
+	return c.do("PUT", room(roomID, "send", "m.room.message", c.txnID()), nil, content, nil)
+}
+
+// Public sends a message to the room.
+func (c *Client) Public(message string) {
+	c.send(c.Room, message)
+}
+
+// Private sends a direct message to given player, the direct room is
+// created the first time.
+func (c *Client) Private(nick, message string) {
+
+	id := c.userID(nick)
+
+	// Can't invite someone we don't know.
+	if !strings.HasPrefix(id, "@") {
+		return
+	}
+
+	c.mutex.Lock()
+	roomID, known := c.direct[id]
+	c.mutex.Unlock()
+
+	if !known {
+
+		var created struct {
+			RoomID string `json:"room_id"`
+		}
+
+		req := map[string]interface{}{
+			"is_direct": true,
+			"invite":    []string{id},
+			"preset":    "trusted_private_chat",
+		}
+
+		if err := c.do("POST", path_CREATE, nil, req, &created); err != nil {
+			return
+		}
+
+		roomID = created.RoomID
+
+		c.mutex.Lock()
+		c.direct[id] = roomID
+		c.mutex.Unlock()
+
+	}
+
+	c.send(roomID, message)
+
+}
+
+// Kick removes a player from the room.
+func (c *Client) Kick(nick, reason string) {
+	c.do("POST", room(c.Room, "kick"), nil, map[string]string{"user_id": c.userID(nick), "reason": reason}, nil)
+}
+
+// Ban bans a player from the room.
+func (c *Client) Ban(nick string) bool {
+
+	id := c.userID(nick)
+
+	if err := c.do("POST", room(c.Room, "ban"), nil, map[string]string{"user_id": id}, nil); err != nil {
+		return false
+	}
+
+	// Remember who this was, banned players leave the room.
+	c.mutex.Lock()
+	c.banned[nick] = id
+	c.mutex.Unlock()
+
+	return true
+}
+
+// UnBan lifts a ban.
+func (c *Client) UnBan(nick string) {
+
+	id := c.userID(nick)
+
+	c.do("POST", room(c.Room, "unban"), nil, map[string]string{"user_id": id}, nil)
+
+	c.mutex.Lock()
+	delete(c.banned, nick)
+	c.mutex.Unlock()
+
+}
+
+// powerLevels is the content of an m.room.power_levels event.
+type powerLevels struct {
+	Users        map[string]int `json:"users"`
+	UsersDefault int            `json:"users_default"`
+	Kick         *int           `json:"kick"`
+	Ban          *int           `json:"ban"`
+}
+
+// level returns the power level of given user.
+func (p *powerLevels) level(id string) int {
+	if l, ok := p.Users[id]; ok {
+		return l
+	}
+	return p.UsersDefault
+}
+
+// required returns the power level needed to kick and ban.
+func (p *powerLevels) required() int {
+
+	// Both default to 50 when missing.
+	kick, ban := 50, 50
+	if p.Kick != nil {
+		kick = *p.Kick
+	}
+	if p.Ban != nil {
+		ban = *p.Ban
+	}
+
+	if kick > ban {
+		return kick
+	}
+	return ban
+}
+
+// powerLevels fetches the power levels of the room.
+func (c *Client) powerLevels() (*powerLevels, error) {
+	p := new(powerLevels)
+	return p, c.do("GET", room(c.Room, "state", "m.room.power_levels"), nil, nil, p)
+}
+
+// IsOperator returns true if the bot's power level allows kicking and
+// banning players.
+func (c *Client) IsOperator() bool {
+
+	p, err := c.powerLevels()
+	if err != nil {
+		return false
+	}
+
+	return p.level(c.UserID) >= p.required()
+}
+
+// IsAdmin returns true if given player's power level allows kicking and
+// banning players.
+func (c *Client) IsAdmin(nick string) bool {
+
+	p, err := c.powerLevels()
+	if err != nil {
+		return false
+	}
+
+	return p.level(c.userID(nick)) >= p.required()
+}
+
+// Identity returns the user ID for given display name.
+func (c *Client) Identity(nick string) string {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.members[nick]
+}
+
+// whoami sets the user ID of the bot.
+func (c *Client) whoami() error {
+
+	var resp struct {
+		UserID string `json:"user_id"`
+	}
+
+	if err := c.do("GET", path_WHOAMI, nil, nil, &resp); err != nil {
+		return err
+	}
+
+	if resp.UserID == "" {
+		return errors.New("matrix: unknown user")
+	}
+
+	c.UserID = resp.UserID
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package matrix
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sorcix/passthebomb/ptb"
+)
+
+// request is a call made to the fake homeserver.
+type request struct {
+	Path string
+	Body map[string]interface{}
+}
+
+// homeserver is a fake Matrix homeserver, syncs return the batches pushed
+// by the test and end Run once there are no more.
+type homeserver struct {
+	*httptest.Server
+	batches  chan string
+	requests chan request
+	levels   atomic.Value // Power levels of the room.
+	quit     chan bool
+}
+
+func newHomeserver(t *testing.T) *homeserver {
+
+	h := &homeserver{
+		batches:  make(chan string),
+		requests: make(chan request, 100),
+		quit:     make(chan bool),
+	}
+
+	h.levels.Store(`{"users":{"@bot:x":100}}`)
+
+	h.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("%s: missing access token", r.URL.Path)
+		}
+
+		path := strings.TrimPrefix(r.URL.EscapedPath(), path_PREFIX)
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch {
+
+		case path == path_WHOAMI:
+			w.Write([]byte(`{"user_id":"@bot:x"}`))
+
+		case path == path_SYNC:
+			var batch string
+			ok := false
+			select {
+			case batch, ok = <-h.batches:
+			case <-h.quit:
+			}
+			if !ok {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"errcode":"M_UNKNOWN","error":"done"}`))
+				return
+			}
+			w.Write([]byte(batch))
+
+		case strings.HasSuffix(path, "/state/m.room.power_levels"):
+			w.Write([]byte(h.levels.Load().(string)))
+
+		case path == path_CREATE:
+			h.requests <- request{path, body}
+			w.Write([]byte(`{"room_id":"!dm:x"}`))
+
+		case body["user_id"] == "@nobody:x":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errcode":"M_FORBIDDEN","error":"not allowed"}`))
+
+		default:
+			h.requests <- request{path, body}
+			w.Write([]byte(`{}`))
+
+		}
+
+	}))
+
+	return h
+}
+
+// Close stops the server, syncs that are waiting for a batch fail.
+func (h *homeserver) Close() {
+	close(h.quit)
+	h.Server.Close()
+}
+
+// expect waits for a request to given path, with a body containing given
+// value. Other requests are skipped.
+func (h *homeserver) expect(t *testing.T, path, key, value string) request {
+
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case r := <-h.requests:
+			if strings.HasPrefix(r.Path, path) && strings.Contains(jsonString(r.Body[key]), value) {
+				return r
+			}
+		case <-timeout:
+			t.Fatalf("no request to %s with %s containing %q", path, key, value)
+		}
+	}
+}
+
+// sync waits until Run handled the previous batch, it asks for the next one
+// once it's done.
+func (h *homeserver) sync() {
+	h.batches <- batch("sync", "", "")
+}
+
+// jsonString returns a JSON value as a string, for matching.
+func jsonString(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// batch returns a sync response with given events in room !r:x.
+func batch(next string, state, timeline string) string {
+	return `{"next_batch":"` + next + `","rooms":{"join":{"!r:x":{` +
+		`"state":{"events":[` + state + `]},` +
+		`"timeline":{"events":[` + timeline + `]}}}}}`
+}
+
+func memberEvent(id, membership, name string) string {
+	return `{"type":"m.room.member","sender":"` + id + `","state_key":"` + id + `",` +
+		`"content":{"membership":"` + membership + `","displayname":"` + name + `"}}`
+}
+
+func messageEvent(id, body string) string {
+	return `{"type":"m.room.message","sender":"` + id + `",` +
+		`"content":{"msgtype":"m.text","body":"` + body + `"}}`
+}
+
+func TestRun(t *testing.T) {
+
+	h := newHomeserver(t)
+	defer h.Close()
+
+	c := NewClient(h.URL+"/", "token", "!r:x")
+	g := ptb.NewGame(c)
+	g.Configure("outbound_burst", "0")
+
+	done := make(chan error)
+	go func() {
+		done <- c.Run(g)
+	}()
+
+	// History is only used to learn who is in the room.
+	h.batches <- batch("s1", memberEvent("@a:x", "join", "Alice")+","+memberEvent("@b:x", "join", "Bob"), messageEvent("@a:x", "!join"))
+	h.sync()
+
+	if c.Identity("Alice") != "@a:x" || c.Identity("Bob") != "@b:x" {
+		t.Fatal("members from history are unknown")
+	}
+
+	go g.Start()
+	h.expect(t, room("!r:x", "send"), "body", "Attentiooooon")
+
+	// Joining sends a private message, in a new direct room.
+	h.batches <- batch("s2", "", messageEvent("@a:x", "!join"))
+	h.expect(t, path_CREATE, "invite", "@a:x")
+	h.expect(t, room("!dm:x", "send"), "body", "enlisted")
+
+	// Display name changes are renames.
+	h.batches <- batch("s3", "", memberEvent("@a:x", "join", "Al"))
+	h.expect(t, room("!r:x", "send"), "body", "Alice is acting like a complete asshole and is now known as Al")
+	h.sync()
+
+	if c.Identity("Al") != "@a:x" || c.Identity("Alice") != "" {
+		t.Fatal("display name change not tracked")
+	}
+
+	// The direct room is used again.
+	c.Private("Al", "hello")
+	r := h.expect(t, path_ROOM, "body", "hello")
+	if !strings.HasPrefix(r.Path, room("!dm:x")) {
+		t.Fatalf("private message sent to %s", r.Path)
+	}
+
+	// Leaving the room leaves the game.
+	h.batches <- batch("s4", "", memberEvent("@a:x", "leave", "Al"))
+	h.expect(t, room("!r:x", "send"), "body", "Al has gone AWOL")
+
+	// Same display name for another user: they're known by their user ID.
+	h.batches <- batch("s5", "", memberEvent("@c:x", "join", "Bob"))
+	h.sync()
+
+	if c.Identity("@c:x") != "@c:x" {
+		t.Fatal("display name used twice")
+	}
+
+	close(h.batches)
+
+	if err := <-done; err == nil {
+		t.Fatal("Run returned without error")
+	}
+
+}
+
+func TestKickBan(t *testing.T) {
+
+	h := newHomeserver(t)
+	defer h.Close()
+
+	c := NewClient(h.URL, "token", "!r:x")
+	c.member(nil, "@a:x", member{Membership: "join", DisplayName: "Alice"}, false)
+
+	c.Kick("Alice", "boom")
+	if r := h.expect(t, room("!r:x", "kick"), "user_id", "@a:x"); r.Body["reason"] != "boom" {
+		t.Fatalf("kick reason is %v", r.Body["reason"])
+	}
+
+	if !c.Ban("Alice") {
+		t.Fatal("ban failed")
+	}
+	h.expect(t, room("!r:x", "ban"), "user_id", "@a:x")
+
+	// Banned players leave the room, but can still be unbanned by name.
+	c.member(nil, "@a:x", member{Membership: "ban"}, false)
+	c.UnBan("Alice")
+	h.expect(t, room("!r:x", "unban"), "user_id", "@a:x")
+
+	if c.Ban("@nobody:x") {
+		t.Fatal("ban succeeded without permission")
+	}
+
+}
+
+func TestPowerLevels(t *testing.T) {
+
+	h := newHomeserver(t)
+	defer h.Close()
+
+	c := NewClient(h.URL, "token", "!r:x")
+	c.UserID = "@bot:x"
+	c.member(nil, "@a:x", member{Membership: "join", DisplayName: "Alice"}, false)
+	c.member(nil, "@b:x", member{Membership: "join", DisplayName: "Bob"}, false)
+
+	tests := []struct {
+		levels   string
+		operator bool
+		admins   []string
+	}{
+		// Kicking and banning need 50 by default.
+		{`{"users":{"@bot:x":50,"@a:x":50}}`, true, []string{"Alice"}},
+		{`{"users":{"@bot:x":49},"users_default":10}`, false, nil},
+		{`{"users_default":50}`, true, []string{"Alice", "Bob"}},
+		// The highest of both counts.
+		{`{"users":{"@bot:x":50,"@a:x":100},"kick":0,"ban":100}`, false, []string{"Alice"}},
+		{`{"users":{"@bot:x":50},"kick":100,"ban":0}`, false, nil},
+	}
+
+	for _, test := range tests {
+
+		h.levels.Store(test.levels)
+
+		if c.IsOperator() != test.operator {
+			t.Errorf("%s: operator should be %v", test.levels, test.operator)
+		}
+
+		for _, nick := range []string{"Alice", "Bob"} {
+			admin := false
+			for _, a := range test.admins {
+				admin = admin || a == nick
+			}
+			if c.IsAdmin(nick) != admin {
+				t.Errorf("%s: %s admin should be %v", test.levels, nick, admin)
+			}
+		}
+
+	}
+
+}
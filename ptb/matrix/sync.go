@@ -0,0 +1,192 @@
+package matrix
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/sorcix/passthebomb/ptb"
+)
+
+// Sync long-polling timeout in milliseconds.
+const tweak_SYNC_TIMEOUT = "30000"
+
+// event is a room event received while syncing.
+type event struct {
+	Type     string          `json:"type"`
+	Sender   string          `json:"sender"`
+	StateKey *string         `json:"state_key"`
+	Content  json.RawMessage `json:"content"`
+}
+
+// member is the content of an m.room.member event.
+type member struct {
+	Membership  string `json:"membership"`
+	DisplayName string `json:"displayname"`
+}
+
+// text is the content of an m.room.message event.
+type text struct {
+	MsgType string `json:"msgtype"`
+	Body    string `json:"body"`
+}
+
+// syncResponse contains the parts of a sync we care about.
+type syncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			State struct {
+				Events []event `json:"events"`
+			} `json:"state"`
+			Timeline struct {
+				Events []event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+// Run syncs with the homeserver and passes messages in the room to the game.
+// Membership changes are mapped to Leave and Rename, history from before Run
+// is only used to learn who is in the room. Joining the room doesn't join the
+// game, players type !join like in any other chat.
+// Run blocks until a request fails.
+func (c *Client) Run(g *ptb.Game) error {
+
+	if c.UserID == "" {
+		if err := c.whoami(); err != nil {
+			return err
+		}
+	}
+
+	since := ""
+
+	for {
+
+		query := url.Values{"timeout": {tweak_SYNC_TIMEOUT}}
+		if since != "" {
+			query.Set("since", since)
+		}
+
+		var resp syncResponse
+
+		if err := c.do("GET", path_SYNC, query, nil, &resp); err != nil {
+			return err
+		}
+
+		r := resp.Rooms.Join[c.Room]
+
+		for _, e := range r.State.Events {
+			c.event(g, e, false)
+		}
+
+		for _, e := range r.Timeline.Events {
+			c.event(g, e, since != "")
+		}
+
+		since = resp.NextBatch
+
+	}
+
+}
+
+// event handles a room event, live events are passed to the game.
+func (c *Client) event(g *ptb.Game, e event, live bool) {
+
+	switch e.Type {
+
+	case "m.room.member":
+		if e.StateKey == nil {
+			return
+		}
+		var m member
+		if json.Unmarshal(e.Content, &m) == nil {
+			c.member(g, *e.StateKey, m, live)
+		}
+
+	case "m.room.message":
+		if !live || e.Sender == c.UserID {
+			return
+		}
+		var t text
+		if json.Unmarshal(e.Content, &t) == nil && t.MsgType == "m.text" {
+			g.Decode(c.nick(e.Sender), t.Body)
+		}
+
+	}
+
+}
+
+// member handles a membership change of given user.
+func (c *Client) member(g *ptb.Game, id string, m member, live bool) {
+
+	c.mutex.Lock()
+	old, known := c.names[id]
+	c.mutex.Unlock()
+
+	switch m.Membership {
+
+	case "join":
+		// Not a game join: people in the room may just want to watch, and
+		// display name changes are join events too.
+		nick := c.displayName(id, m.DisplayName)
+
+		// Rename before forgetting the old name, the game still knows the
+		// player by it.
+		if live && known && old != nick {
+			g.Rename(old, nick)
+		}
+
+		c.mutex.Lock()
+		delete(c.members, old)
+		c.members[nick] = id
+		c.names[id] = nick
+		c.mutex.Unlock()
+
+	case "leave", "ban":
+		if !known {
+			return
+		}
+
+		if live {
+			g.Leave(old)
+		}
+
+		c.mutex.Lock()
+		delete(c.members, old)
+		delete(c.names, id)
+		c.mutex.Unlock()
+
+	}
+
+}
+
+// displayName returns the nickname for a user, this is their display name
+// unless it's empty or used by someone else.
+func (c *Client) displayName(id, name string) string {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if name == "" {
+		return id
+	}
+
+	if other, taken := c.members[name]; taken && other != id {
+		return id
+	}
+
+	return name
+}
+
+// nick returns the nickname for given user ID.
+func (c *Client) nick(id string) string {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if nick, known := c.names[id]; known {
+		return nick
+	}
+
+	return id
+}
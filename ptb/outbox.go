@@ -0,0 +1,234 @@
+package ptb
+
+import (
+	"sync"
+	"time"
+)
+
+// Outbound messages
+const (
+	tweak_COALESCE_LENGTH = 400 // Maximum length of messages combined into one line: 0=never combine.
+	tweak_OPERATOR_CACHE  = 60  // Time operator status is remembered in seconds.
+)
+
+// message is waiting to be sent by the outbox.
+type message struct {
+	nick  string // Receiver, or empty for public messages.
+	text  string // Message.
+	do    func() // Called instead of sending a message, like a kick.
+	count int    // Number of queued messages combined into this one.
+}
+
+// outbox wraps a Chat and sends messages in the background, so the game
+// doesn't have to wait for the network while holding its lock.
+// Messages are sent in order, bursts are combined into fewer lines and
+// throttled to avoid flooding the room.
+type outbox struct {
+	Chat
+	mutex   sync.Mutex
+	queue   []message
+	wake    chan bool
+	pending sync.WaitGroup
+	bucket  bucket
+
+	burst    int           // Copy of Config.OutboundBurst.
+	interval time.Duration // Copy of Config.OutboundInterval.
+	coalesce int           // Copy of Config.CoalesceLength.
+
+	operator bool      // Cached result of IsOperator.
+	checked  time.Time // Time IsOperator was last asked.
+	closed   bool      // True once the sender stopped.
+}
+
+// newOutbox returns an outbox for given chat and starts sending.
+func newOutbox(chat Chat, config *Config) *outbox {
+
+	o := &outbox{Chat: chat}
+	o.wake = make(chan bool, 1)
+	o.configure(config)
+
+	go o.run()
+
+	return o
+}
+
+// configure copies the outbound settings, the game calls this when they
+// change so the outbox never reads the config without the game lock.
+func (o *outbox) configure(config *Config) {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.burst = config.OutboundBurst
+	o.interval = config.OutboundInterval
+	o.coalesce = config.CoalesceLength
+
+}
+
+// IsOperator returns the operator status the chat reported last, asking
+// the chat again in the background once in a while.
+func (o *outbox) IsOperator() bool {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if time.Now().Sub(o.checked) > tweak_OPERATOR_CACHE*time.Second {
+		o.checked = time.Now()
+		go o.refresh()
+	}
+
+	return o.operator
+}
+
+// refresh asks the chat for the operator status, this might block.
+func (o *outbox) refresh() {
+
+	operator := o.Chat.IsOperator()
+
+	o.mutex.Lock()
+	o.operator = operator
+	o.checked = time.Now()
+	o.mutex.Unlock()
+
+}
+
+// Public queues a message to all players.
+func (o *outbox) Public(text string) {
+	o.push(message{text: text})
+}
+
+// Private queues a message to given player.
+func (o *outbox) Private(nick, text string) {
+	o.push(message{nick: nick, text: text})
+}
+
+// Kick queues a kick, so it happens after the messages leading up to it.
+func (o *outbox) Kick(nick, reason string) {
+	o.Do(func() {
+		o.Chat.Kick(nick, reason)
+	})
+}
+
+// Do queues a call to the chat, like a ban, so it happens in order with
+// the messages and the game doesn't wait for it.
+func (o *outbox) Do(f func()) {
+	o.push(message{do: f})
+}
+
+// Flush waits until all queued messages are sent.
+func (o *outbox) Flush() {
+	o.pending.Wait()
+}
+
+// push adds a message to the queue and wakes up the sender.
+func (o *outbox) push(m message) {
+
+	m.count = 1
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	// Nobody left to send it.
+	if o.closed {
+		return
+	}
+
+	o.queue = append(o.queue, m)
+	o.pending.Add(1)
+
+	select {
+	case o.wake <- true:
+	default:
+	}
+
+}
+
+// close stops the sender once the queue is empty, messages queued after
+// this are dropped.
+func (o *outbox) close() {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if !o.closed {
+		o.closed = true
+		close(o.wake)
+	}
+
+}
+
+// run sends queued messages until the queue is empty, and waits for more.
+func (o *outbox) run() {
+
+	for range o.wake {
+		for o.waiting() {
+			o.throttle()
+			o.send(o.pop())
+		}
+	}
+
+}
+
+// waiting returns true if there are messages in the queue.
+func (o *outbox) waiting() bool {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return len(o.queue) > 0
+}
+
+// throttle blocks until another message can be sent.
+func (o *outbox) throttle() {
+
+	o.mutex.Lock()
+	burst, interval := o.burst, o.interval
+	o.mutex.Unlock()
+
+	for !o.bucket.take(burst, interval) {
+		time.Sleep(o.bucket.wait(interval))
+	}
+
+}
+
+// pop takes the first message from the queue, combined with the messages
+// after it that go to the same receiver.
+func (o *outbox) pop() message {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	m := o.queue[0]
+	o.queue = o.queue[1:]
+
+	for m.do == nil && len(o.queue) > 0 {
+
+		next := o.queue[0]
+
+		if next.do != nil || next.nick != m.nick || len(m.text)+len(next.text)+1 > o.coalesce {
+			break
+		}
+
+		m.text = m.text + " " + next.text
+		m.count++
+		o.queue = o.queue[1:]
+	}
+
+	return m
+}
+
+// send passes a message to the chat.
+func (o *outbox) send(m message) {
+
+	defer o.pending.Add(-m.count)
+
+	switch {
+	case m.do != nil:
+		m.do()
+	case m.nick == "":
+		o.Chat.Public(m.text)
+	default:
+		o.Chat.Private(m.nick, m.text)
+	}
+
+}
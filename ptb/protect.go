@@ -0,0 +1,84 @@
+package ptb
+
+// storage keys for protected players and opt-outs
+const (
+	storage_PROTECTED = "protected"
+	storage_OPTOUTS   = "optouts"
+)
+
+// protectedPlayers returns players the game may not kick or ban, loading them from
+// storage if needed.
+func (g *Game) protectedPlayers() map[string]bool {
+
+	if g.Protected == nil {
+		g.Protected = make(map[string]bool)
+		g.load(storage_PROTECTED, &g.Protected)
+	}
+
+	return g.Protected
+}
+
+// optOuts returns players that never want to join, loading them from storage
+// if needed.
+func (g *Game) optOuts() map[string]bool {
+
+	if g.OptOuts == nil {
+		g.OptOuts = make(map[string]bool)
+		g.load(storage_OPTOUTS, &g.OptOuts)
+	}
+
+	return g.OptOuts
+}
+
+// protected returns true if the game may not kick or ban given player.
+func (g *Game) protected(p *Player) bool {
+
+	// Nicknames only count for players we can't identify.
+	for _, nick := range g.Config.Protected {
+		if (!p.nickID && nick == p.ID) || (p.nickID && sanitizeNick(nick) == p.sanitizedNick) {
+			return true
+		}
+	}
+
+	return g.protectedPlayers()[p.ID]
+}
+
+// optedOut returns true if given nickname never wants to join.
+func (g *Game) optedOut(nick string) bool {
+	return g.optOuts()[g.key(nick)]
+}
+
+// Protect changes whether the game may kick or ban given nickname.
+// Protected players only receive a soft penalty.
+func (g *Game) Protect(nick string, protect bool) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if protect {
+		g.protectedPlayers()[g.key(nick)] = true
+	} else {
+		delete(g.protectedPlayers(), g.key(nick))
+	}
+
+	g.save(storage_PROTECTED, g.Protected)
+
+}
+
+// OptOut changes whether given nickname can join games.
+func (g *Game) OptOut(nick string, out bool) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if out {
+		g.optOuts()[g.key(nick)] = true
+		g.chat.Private(nick, text_PLAYER_OPTED_OUT)
+	} else {
+		delete(g.optOuts(), g.key(nick))
+		g.chat.Private(nick, text_PLAYER_OPTED_IN)
+	}
+
+	g.save(storage_OPTOUTS, g.OptOuts)
+
+}
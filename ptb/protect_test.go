@@ -0,0 +1,36 @@
+package ptb
+
+import (
+	"testing"
+)
+
+func TestProtected(t *testing.T) {
+
+	g, _ := newTestGame()
+	g.Config.Protected = []string{"@alice:x", "Bob"}
+	g.Protected = map[string]bool{"@carol:x": true, "dave": true}
+
+	tests := []struct {
+		nick      string
+		id        string // Stable ID of the player, empty if unknown.
+		protected bool
+	}{
+		// Identified players only count by ID.
+		{"Alice", "@alice:x", true},
+		{"Bob", "@mallory:x", false},
+		{"Carol", "@carol:x", true},
+		// Nicknames count for players we can't identify.
+		{"Bob", "", true},
+		{"BOB ", "", true},
+		{"Alice", "", false},
+		{"Dave", "", true},
+		{"Erin", "", false},
+	}
+
+	for _, test := range tests {
+		if g.protected(player(test.nick, test.id)) != test.protected {
+			t.Errorf("%q %q: protected should be %v", test.nick, test.id, test.protected)
+		}
+	}
+
+}
@@ -0,0 +1,227 @@
+package ptb
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Moderation
+const (
+	tweak_MUTE_TIME   = 5             // Mute time in minutes.
+	tweak_VICTIM_NICK = "bomb-victim" // Nickname given to players after explosion.
+)
+
+// Muter can be implemented by a Chat that is able to silence players.
+type Muter interface {
+	Mute(nick string) bool // Mutes given nickname, false if not allowed.
+	UnMute(nick string)    // Unmutes given nickname.
+}
+
+// NickChanger can be implemented by a Chat that is able to change nicknames.
+type NickChanger interface {
+	ChangeNick(nick, newNick string) bool // Changes the nickname of a player, false if not allowed.
+}
+
+// Punisher handles a player the bomb exploded on.
+type Punisher interface {
+	Punish(g *Game, p *Player) bool // Returns false if the chat didn't allow the punishment.
+}
+
+// DefaultPunisher returns the punishment used by a new game.
+func DefaultPunisher() Punisher {
+
+	punishers := make(Punishers, 0, 2)
+
+	if tweak_BAN {
+		punishers = append(punishers, &BanPunisher{Duration: tweak_BAN_TIME * time.Second})
+	}
+
+	if tweak_KICK {
+		punishers = append(punishers, new(KickPunisher))
+	}
+
+	return punishers
+}
+
+// ParsePunisher returns the punishment with given name, several names can be
+// combined with a plus sign: "ban+kick". Known names are kick, ban, mute,
+// rename, shame and none.
+func ParsePunisher(name string) (Punisher, bool) {
+
+	var punishers Punishers
+
+	for _, n := range strings.Split(strings.ToLower(name), "+") {
+		switch strings.TrimSpace(n) {
+		case "kick":
+			punishers = append(punishers, new(KickPunisher))
+		case "ban":
+			punishers = append(punishers, &BanPunisher{Duration: tweak_BAN_TIME * time.Second})
+		case "mute":
+			punishers = append(punishers, &MutePunisher{Duration: tweak_MUTE_TIME * time.Minute})
+		case "rename":
+			punishers = append(punishers, &RenamePunisher{Nick: tweak_VICTIM_NICK})
+		case "shame":
+			punishers = append(punishers, new(ShamePunisher))
+		case "none":
+			punishers = append(punishers, new(NoPunisher))
+		default:
+			return nil, false
+		}
+	}
+
+	return punishers, true
+}
+
+// punish handles a player the bomb exploded on. Players are publicly shamed
+// if the chat didn't allow any punishment.
+func (g *Game) punish(p *Player) {
+
+	// Protected players get away with some public shaming.
+	if g.protected(p) {
+		new(ShamePunisher).Punish(g, p)
+		return
+	}
+
+	if g.Config.Punisher != nil && g.Config.Punisher.Punish(g, p) {
+		return
+	}
+
+	new(ShamePunisher).Punish(g, p)
+
+}
+
+// Punishers combines several punishments, all of them are applied in order.
+type Punishers []Punisher
+
+// Punish applies all punishments, returns true if any of them succeeded.
+func (ps Punishers) Punish(g *Game, p *Player) (ok bool) {
+	for _, punisher := range ps {
+		if punisher.Punish(g, p) {
+			ok = true
+		}
+	}
+	return
+}
+
+// KickPunisher kicks the player from the room.
+type KickPunisher struct{}
+
+func (k *KickPunisher) Punish(g *Game, p *Player) bool {
+
+	if !g.chat.IsOperator() {
+		return false
+	}
+
+	g.chat.Kick(p.Nick, text_BOMB_EXPLODE)
+	return true
+}
+
+// BanPunisher bans the player for a while, combine with KickPunisher to
+// prevent automatic rejoins.
+type BanPunisher struct {
+	Duration time.Duration // Time till the player is unbanned.
+}
+
+func (b *BanPunisher) Punish(g *Game, p *Player) bool {
+
+	nick := p.Nick
+
+	if !g.chat.IsOperator() {
+		return false
+	}
+
+	// Messages are queued from the outbox, the game lock isn't held there.
+	g.chat.Do(func() {
+		if !g.chat.Chat.Ban(nick) {
+			shame(g.chat, nick)
+			return
+		}
+
+		// Schedule unban!
+		g.mutex.Lock()
+		g.schedule(action_UNBAN, nick, b.Duration)
+		g.mutex.Unlock()
+	})
+
+	return true
+}
+
+// MutePunisher silences the player for a while, if the chat supports it.
+type MutePunisher struct {
+	Duration time.Duration // Time till the player is unmuted.
+}
+
+func (m *MutePunisher) Punish(g *Game, p *Player) bool {
+
+	muter, ok := g.chat.Chat.(Muter)
+	nick := p.Nick
+
+	if !ok {
+		return false
+	}
+
+	// Messages are queued from the outbox, the game lock isn't held there.
+	g.chat.Do(func() {
+		if !muter.Mute(nick) {
+			shame(g.chat, nick)
+			return
+		}
+		g.chat.Public(text_BOMB_EXPLODE)
+		g.chat.Public(fmt.Sprintf(text_BOMB_EXPLODE_MUTE, nick, m.Duration/time.Minute))
+
+		// Schedule unmute!
+		g.mutex.Lock()
+		g.schedule(action_UNMUTE, nick, m.Duration)
+		g.mutex.Unlock()
+	})
+
+	return true
+}
+
+// RenamePunisher changes the player's nickname, if the chat supports it.
+type RenamePunisher struct {
+	Nick string // New nickname.
+}
+
+func (r *RenamePunisher) Punish(g *Game, p *Player) bool {
+
+	changer, ok := g.chat.Chat.(NickChanger)
+	nick, victim := p.Nick, r.Nick
+
+	if !ok {
+		return false
+	}
+
+	g.chat.Do(func() {
+		if !changer.ChangeNick(nick, victim) {
+			shame(g.chat, nick)
+			return
+		}
+		g.chat.Public(text_BOMB_EXPLODE)
+		g.chat.Public(fmt.Sprintf(text_BOMB_EXPLODE_RENAME, nick, victim))
+	})
+
+	return true
+}
+
+// ShamePunisher only mentions the player in the room.
+type ShamePunisher struct{}
+
+func (s *ShamePunisher) Punish(g *Game, p *Player) bool {
+	shame(g.chat, p.Nick)
+	return true
+}
+
+// shame mentions given nickname in the room.
+func shame(chat Chat, nick string) {
+	chat.Public(text_BOMB_EXPLODE)
+	chat.Public(fmt.Sprintf(text_BOMB_EXPLODE_NOOP, nick))
+}
+
+// NoPunisher lets players get away with it, nothing is shown.
+type NoPunisher struct{}
+
+func (n *NoPunisher) Punish(g *Game, p *Player) bool {
+	return true
+}
@@ -0,0 +1,95 @@
+package ptb
+
+import (
+	"fmt"
+	"math"
+)
+
+// Rating
+const (
+	tweak_RATING        = 1500 // Rating for new players.
+	tweak_RATING_FACTOR = 32   // Maximum rating change per game (Elo K-factor).
+)
+
+// updateRatings changes player ratings based on their position on the
+// scoreboard. Every player plays a match against every other player, the
+// one ranked higher wins.
+func (g *Game) updateRatings() {
+
+	n := len(g.Scores)
+	if n < 2 {
+		return
+	}
+
+	// Use ratings from before this game for all calculations.
+	before := make([]float64, n)
+	for i, c := range g.Scores {
+		before[i] = g.record(c.Player).Rating
+	}
+
+	for i, c := range g.Scores {
+
+		delta := 0.0
+
+		for j, o := range g.Scores {
+
+			if i == j {
+				continue
+			}
+
+			expected := 1 / (1 + math.Pow(10, (before[j]-before[i])/400))
+
+			actual := 0.0
+			if c.Score == o.Score {
+				actual = 0.5
+			} else if i < j {
+				actual = 1
+			}
+
+			delta = delta + actual - expected
+		}
+
+		r := g.record(c.Player)
+		r.Rating = before[i] + tweak_RATING_FACTOR*delta/float64(n-1)
+		c.Player.Rating = r.Rating
+	}
+
+}
+
+// lowestRated returns the player with the lowest rating.
+func (g *Game) lowestRated() (lowest *Player) {
+
+	for _, p := range g.Players {
+		if lowest == nil || g.record(p).Rating < g.record(lowest).Rating {
+			lowest = p
+		}
+	}
+
+	return
+}
+
+// Rating shows the rating of given player, or the sender if nick is empty.
+func (g *Game) Rating(sender, nick string) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if nick == "" {
+		nick = sender
+	}
+
+	id := g.key(nick)
+	if p := g.byNick(nick); p != nil {
+		id = p.ID
+	}
+
+	r, known := g.records()[id]
+
+	if !known {
+		g.chat.Public(fmt.Sprintf(text_RATING_UNKNOWN, nick))
+		return
+	}
+
+	g.chat.Public(fmt.Sprintf(text_RATING, nick, int(r.Rating), r.Rounds))
+
+}
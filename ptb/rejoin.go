@@ -0,0 +1,74 @@
+package ptb
+
+import (
+	"fmt"
+	"time"
+)
+
+// Rejoining
+const (
+	tweak_REJOIN_GRACE = 60 // Time a deserter can rejoin and keep their progress in seconds.
+)
+
+// deserter returns a player that recently left and can rejoin with given
+// nickname. Players with a stable ID can come back using another nickname.
+func (g *Game) deserter(nick string) *Player {
+
+	if g.state != state_PLAYING {
+		return nil
+	}
+
+	p := g.Players[g.key(nick)]
+
+	if p == nil || !p.Deserted || p.Dead || time.Now().Sub(p.left) > g.Config.RejoinGrace {
+		return nil
+	}
+
+	return p
+}
+
+// checkPlayers ends the game if too few players are left, the caller should
+// hold the game lock. Deserters count until their grace period expires, so
+// the game doesn't end before they had a chance to rejoin.
+func (g *Game) checkPlayers() {
+
+	if g.state != state_PLAYING {
+		return
+	}
+
+	active := g.activePlayers()
+	n := active
+
+	for _, p := range g.Players {
+		if p.Deserted && !p.Dead && time.Now().Sub(p.left) <= g.Config.RejoinGrace {
+			n++
+		}
+	}
+
+	if active > 0 && n >= g.Config.MinPlayers {
+		return
+	}
+
+	g.chat.Public(text_END_TOO_FEW)
+
+	if g.Config.AbortOnLeave {
+		g.abort()
+	} else {
+		g.early = true
+		g.end()
+	}
+
+}
+
+// rejoin restores a deserter, they keep their turns and hold time.
+func (g *Game) rejoin(p *Player, nick string) {
+
+	p.Deserted = false
+
+	// The player might have come back with a different nickname.
+	p.Nick = nick
+	p.sanitizedNick = sanitizeNick(nick)
+
+	g.chat.Public(fmt.Sprintf(text_PLAYER_REJOINED, p.Nick))
+
+}
@@ -14,10 +14,11 @@ type ScoreCard struct {
 }
 
 // ScoreBoard represents the list of players and their scores.
+// Sorting puts the highest score first.
 type ScoreBoard []*ScoreCard
 
 func (sb ScoreBoard) Len() int           { return len(sb) }
-func (sb ScoreBoard) Less(i, j int) bool { return sb[i].Score < sb[j].Score }
+func (sb ScoreBoard) Less(i, j int) bool { return sb[i].Score > sb[j].Score }
 func (sb ScoreBoard) Swap(i, j int)      { sb[i], sb[j] = sb[j], sb[i] }
 
 // DurationScore scores players based on the time they've held the bomb.
@@ -33,6 +34,8 @@ func DurationScore(g *Game, p *Player) (c *ScoreCard) {
 	}
 
 	c.Score = uint64(p.Duration / time.Second)
+
+	penalize(g, c)
 	return
 }
 
@@ -49,6 +52,8 @@ func MeanDurationScore(g *Game, p *Player) (c *ScoreCard) {
 	}
 
 	c.Score = uint64(p.MeanDuration / time.Second)
+
+	penalize(g, c)
 	return
 }
 
@@ -71,6 +76,12 @@ func DefuseScore(g *Game, p *Player) (c *ScoreCard) {
 		c.Score = c.Score + 60*5
 	}
 
+	// Helping someone defuse is worth a minute bonus.
+	if p.Helped {
+		c.Score = c.Score + 60
+	}
+
+	penalize(g, c)
 	return
 }
 
@@ -93,6 +104,11 @@ func ComplexScore(g *Game, p *Player) (c *ScoreCard) {
 		c.Score = c.Score + 60
 	}
 
+	// Helping someone defuse is worth a minute bonus.
+	if p.Helped {
+		c.Score = c.Score + 60
+	}
+
 	// The player that defused the bomb gets 5 minutes bonus!
 	if p.Defused {
 		c.Score = c.Score + 60*5
@@ -103,6 +119,7 @@ func ComplexScore(g *Game, p *Player) (c *ScoreCard) {
 		c.Score = c.Score + 60*5
 	}
 
+	penalize(g, c)
 	return
 
 }
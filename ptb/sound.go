@@ -0,0 +1,60 @@
+package ptb
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Remaining time below which the bomb sounds become more intense.
+const (
+	sound_MEDIUM = 2 * time.Minute
+	sound_SHORT  = 30 * time.Second
+)
+
+// jitter returns d randomly scaled between 50% and 150%.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)+1))
+}
+
+// scheduleSound picks the next time the bomb makes a sound.
+// Sounds get more frequent as detonation comes closer.
+func (g *Game) scheduleSound() {
+
+	interval := jitter(g.bomb.detonation.Sub(time.Now()) / 3)
+
+	if interval < g.Config.SoundMinInterval {
+		interval = g.Config.SoundMinInterval
+	}
+	if interval > g.Config.SoundMaxInterval {
+		interval = g.Config.SoundMaxInterval
+	}
+
+	g.bomb.nextSound = time.Now().Add(interval)
+
+}
+
+// checkSound plays a bomb sound if it's time to do so.
+// Levels are chosen with some randomness so players can't tell the exact time left.
+func (g *Game) checkSound() {
+
+	if !g.Config.Sounds || time.Now().Before(g.bomb.nextSound) {
+		return
+	}
+
+	remaining := jitter(g.bomb.detonation.Sub(time.Now()))
+
+	switch {
+	case remaining < sound_SHORT:
+		g.chat.Public(text_BOMB_SOUND_SHORT)
+	case remaining < sound_MEDIUM:
+		g.chat.Public(text_BOMB_SOUND_MEDIUM)
+	default:
+		g.chat.Public(text_BOMB_SOUND_LONG)
+	}
+
+	g.scheduleSound()
+
+}
@@ -0,0 +1,68 @@
+package ptb
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Storage keeps data that has to survive multiple games and restarts.
+type Storage interface {
+	Load(key string, v interface{}) error // Reads data saved with given key into v.
+	Save(key string, v interface{}) error // Saves v with given key.
+}
+
+// FileStorage stores data as JSON files in a directory.
+type FileStorage string
+
+// Load reads data from the file for given key.
+// Keys without saved data leave v untouched.
+func (dir FileStorage) Load(key string, v interface{}) error {
+
+	data, err := ioutil.ReadFile(dir.path(key))
+
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// Save writes data to the file for given key.
+func (dir FileStorage) Save(key string, v interface{}) error {
+
+	data, err := json.MarshalIndent(v, jsonPrefix, jsonIndent)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so we never leave half a file behind.
+	tmp := dir.path(key) + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, dir.path(key))
+}
+
+func (dir FileStorage) path(key string) string {
+	return filepath.Join(string(dir), key+".json")
+}
+
+// load reads data from storage if the game has any.
+func (g *Game) load(key string, v interface{}) {
+	if g.Storage != nil {
+		g.Storage.Load(key, v)
+	}
+}
+
+// save writes data to storage if the game has any.
+// Storage errors shouldn't interrupt the game, data is kept in memory anyway.
+func (g *Game) save(key string, v interface{}) {
+	if g.Storage != nil {
+		g.Storage.Save(key, v)
+	}
+}
@@ -22,9 +22,24 @@ const (
 	// END
 	//
 
+	// Public; Not enough players left.
+	text_END_TOO_FEW = "Recruits, too many of you went AWOL! We can't go on like this."
+
+	// Public; Game aborted without results.
+	text_END_ABORTED = "Mission aborted! Nobody wins this round."
+
 	// Public; player held the bomb the longest. (%s = winner nickname)
 	text_END_WINNER = "Congratulations %s! You've won this round!"
 
+	// Public; player rating. (%s = nickname; %d = rating; %d = games played)
+	text_RATING = "%s has a rating of %d after %d games."
+
+	// Public; player without rating. (%s = nickname)
+	text_RATING_UNKNOWN = "Never heard of %s, must be a new recruit."
+
+	// Public; achievement unlocked. (%s = nickname; %s = achievement; %s = description)
+	text_ACHIEVEMENT = "Medal ceremony! %s earned the %s badge: %s."
+
 	//
 	// HELP (shown during join)
 	//
@@ -54,6 +69,21 @@ const (
 	// Public; Player joins late (%s = nickname)
 	text_PLAYER_JOINED_LATE = "Attention platoon! %s joined the game late!"
 
+	// Private; Player can't join anymore.
+	text_PLAYER_JOIN_REFUSED = "Too late, recruit! Wait for the next round."
+
+	// Private; Player opted out, or tries to join after opting out.
+	text_PLAYER_OPTED_OUT = "You're exempt from duty. (" + cmd_PREFIX + cmd_OPT_IN + " to enlist again)"
+
+	// Private; Player opted in again.
+	text_PLAYER_OPTED_IN = "Welcome back, recruit! You can join games again."
+
+	// Private; Player sends commands too fast.
+	text_LIMITED = "Slow down, recruit! Your commands are ignored for a moment."
+
+	// Public; Deserter came back. (%s = nickname)
+	text_PLAYER_REJOINED = "Look who's back! %s returns to the platoon."
+
 	// Public; Player changed name. (first %s = old nickname, second %s = new nickname)
 	text_PLAYER_RENAME = "Recruits, %s is acting like a complete asshole and is now known as %s!"
 
@@ -73,9 +103,27 @@ const (
 	// Public; Bomb dropped (%s = wrong target)
 	text_BOMB_DROPPED = "No! %s is not in your team, recruit! BOMB DROPPED! (" + cmd_PREFIX + cmd_PICK_UP + ")"
 
+	// Public; Target not found, but there's a similar nickname. (first %s = thrower; second %s = suggestion)
+	text_BOMB_SUGGEST = "Who? Use your eyes, %s! Did you mean %s?"
+
 	// Public; Player has picked up a dropped bomb. (%s = nickname)
 	text_BOMB_PICKED_UP = "%s has picked up the bomb!"
 
+	// Private; Holder will lose the bomb soon. (%d = seconds left)
+	text_HOLD_WARNING = "Get rid of that bomb, recruit! You have %d seconds before I take it away from you!"
+
+	// Public; Bomb taken from the holder and passed on. (first %s = holder; second %s = target)
+	text_HOLD_PASSED = "%s fell asleep on duty! The bomb has been handed to %s!"
+
+	// Public; Bomb taken from the holder and dropped. (%s = holder)
+	text_HOLD_DROPPED = "%s fell asleep on duty! BOMB DROPPED! (" + cmd_PREFIX + cmd_PICK_UP + ")"
+
+	// Public; Bomb can't be thrown yet. (%s = nickname)
+	text_BOMB_STUCK = "The bomb is stuck to your hands, %s! Wait a bit before throwing it."
+
+	// Public; Holder left the game. (%s = nickname)
+	text_BOMB_DROPPED_DESERTER = "%s ran off and dropped the bomb! (" + cmd_PREFIX + cmd_PICK_UP + ")"
+
 	// Public; Kick message when the bomb explodes
 	text_BOMB_EXPLODE = "beep beep beep beeeeeeeeeep *BOOOOOOOM*"
 
@@ -85,6 +133,15 @@ const (
 	// Public; Bomb explodes but bot can't kick the player. (%s = nick)
 	text_BOMB_EXPLODE_NOOP = "The bomb exploded in %s's face!"
 
+	// Public; Bomb explodes after the holder left. (%s = nick)
+	text_BOMB_EXPLODE_DESERTER = "The bomb exploded in %s's face while running away!"
+
+	// Public; Bomb explodes and the player is muted. (%s = nick; %d = minutes)
+	text_BOMB_EXPLODE_MUTE = "The bomb blew %s's vocal cords away! Silence for %d minutes."
+
+	// Public; Bomb explodes and the player is renamed. (first %s = nick; second %s = new nick)
+	text_BOMB_EXPLODE_RENAME = "The bomb exploded in %s's face! We'll call you %s from now on."
+
 	// Public; Bomb sounds, long time.
 	text_BOMB_SOUND_LONG = "[BOMB] tsssssss..."
 
@@ -94,6 +151,31 @@ const (
 	// Public; Bomb sounds, close to detonation..
 	text_BOMB_SOUND_SHORT = "[BOMB] BEEP BEEP BEEP BEEP"
 
+	//
+	// BOMB VARIANTS
+	//
+
+	// Public; Sticky bomb announcement. (%d = seconds it sticks)
+	text_BOMB_STICKY = "Careful recruits, this one is sticky! It can't be thrown for %d seconds after catching it."
+
+	// Public; Cluster bomb announcement.
+	text_BOMB_CLUSTER = "Careful recruits, this is a cluster bomb! Cut the wrong wire and it splits."
+
+	// Public; Cluster bomb splits. (%s = nickname of the player getting the other part)
+	text_BOMB_CLUSTER_SPLIT = "The bomb splits in two! The other part lands with %s and keeps ticking!"
+
+	// Public; Countdown bomb announcement.
+	text_BOMB_COUNTDOWN = "Lucky you, recruits! This bomb has a working display."
+
+	// Public; Countdown bomb time left. (%d = seconds)
+	text_BOMB_COUNTDOWN_TICK = "[BOMB] %d seconds"
+
+	// Public; Shrapnel bomb announcement.
+	text_BOMB_SHRAPNEL = "Careful recruits, this bomb is packed with shrapnel! Keep your distance when throwing."
+
+	// Public; Shrapnel hits the previous holder. (%s = nickname)
+	text_BOMB_SHRAPNEL_HIT = "Shrapnel flies everywhere and hits %s!"
+
 	//
 	// DEFUSE
 	//
@@ -101,14 +183,77 @@ const (
 	// Public; Player already tried to defuse.. (%s = nickname)
 	text_DEFUSE_TRIED = "Sorry %s, you've had your chance! We won't let you mess up twice!"
 
-	// Public; Selected a wire that doesn't exist. (%d = wire number)
-	text_DEFUSE_ERROR = "You idiot! There is no wire %d! Don't they learn you how to count these days?"
+	// Public; Selected a wire that doesn't exist, or an answer that isn't a number. (%s = answer)
+	text_DEFUSE_ERROR = "You idiot! There is no %s on this thing! Don't they learn you how to count these days?"
 
 	// Public; Can't defuse (%s = nickname)
 	text_DEFUSE_DISABLED = "Sorry %s, it seems to be impossible to defuse this bomb."
 
-	// Public; Defuse info message
-	text_DEFUSE = "Feeling lucky, Cadet? There are %d wires, which one would you like to cut? (" + cmd_PREFIX + cmd_CUT + " <number>)"
+	// Public; Defuse info message (%d = number of wires; %s = wire list)
+	text_DEFUSE = "Feeling lucky, Cadet? There are %d wires, which one would you like to cut? (" + cmd_PREFIX + cmd_CUT + " <number>) %s"
+
+	// Public; Clue for the wire puzzle. (%s = clue)
+	text_DEFUSE_CLUE = "The manual says: %s"
+
+	// Public; Code lock info message (%s = sequence)
+	text_DEFUSE_CODE = "This bomb has a code lock! The display shows %s, ... Enter the next number! (" + cmd_PREFIX + cmd_CODE + " <number>)"
+
+	// Public; Question info message (%d = seconds; %s = question)
+	text_DEFUSE_QUESTION = "Quick, Cadet! You have %d seconds to answer: what is %s? (" + cmd_PREFIX + cmd_ANSWER + " <number>)"
+
+	// Clue; Color of the right wire. (%s = color)
+	text_CLUE_COLOR = "The right wire is %s."
+
+	// Clue; Right wire has an even number.
+	text_CLUE_EVEN = "The right wire has an even number."
+
+	// Clue; Right wire has an odd number.
+	text_CLUE_ODD = "The right wire has an odd number."
+
+	// Clue; Right wire is in the first half.
+	text_CLUE_FIRST_HALF = "The right wire is in the first half."
+
+	// Clue; Right wire is in the second half.
+	text_CLUE_SECOND_HALF = "The right wire is in the second half."
+
+	// Clue; Color of the wire before the right one. (%s = color)
+	text_CLUE_AFTER = "The right wire comes right after a %s wire."
+
+	// Clue; Number of digits. (%d = digits)
+	text_CLUE_DIGITS = "The answer has %d digits."
+
+	// Clue; Last digit. (%d = digit)
+	text_CLUE_LAST_DIGIT = "The answer ends with a %d."
+
+	// Clue; Even number.
+	text_CLUE_NUMBER_EVEN = "The answer is even."
+
+	// Clue; Odd number.
+	text_CLUE_NUMBER_ODD = "The answer is odd."
+
+	// Clue; Range. (first %d = lower bound; second %d = upper bound)
+	text_CLUE_RANGE = "The answer is between %d and %d."
+
+	// Clue; Red wires explode.
+	text_CLUE_RED_BLUE = "Never cut a red wire if there are more than 3 blue ones."
+
+	// Public; Other players received hints. (%s = nickname)
+	text_HINT_COOP = "Listen up, recruits! Some of you received intel about this bomb. Help %s out!"
+
+	// Private; Hint for a helper. (first %s = nickname defusing; second %s = clue)
+	text_HINT = "Psst! %s is defusing the bomb. Intel says: %s Tell them, quick!"
+
+	// Public; Player tries to throw while defusing. (%s = nickname)
+	text_DEFUSE_LOCKED = "Finish what you started, %s! You can't throw the bomb while defusing."
+
+	// Public; Player didn't answer in time. (%s = nickname)
+	text_DEFUSE_TIMEOUT = "Wake up, %s! You're taking way too long!"
+
+	// Public; Player can answer again. (%d = answers left; %s = nickname)
+	text_DEFUSE_CUTS_LEFT = "You have %d more tries, %s."
+
+	// Public; Defuse attempt over, bomb can be thrown again. (%s = nickname)
+	text_DEFUSE_OVER = "Put down the pliers, %s. Better get rid of that bomb now!"
 
 	// Public; The wire was already cut by another player.
 	text_DEFUSE_DUPLICATE = "You idiot! This wire was already cut.."
@@ -125,6 +270,117 @@ const (
 	// Public; Player defused (%s = nickname)
 	text_DEFUSE_SUCCESS = "Amazing! Private %s defused the bomb, you deserve a medal!"
 
+	//
+	// ITEMS
+	//
+
+	// Private; Player received an item. (%s = item)
+	text_ITEM_RECEIVED = "Supplies! You received a %s. (" + cmd_PREFIX + cmd_USE + " <item> [nick])"
+
+	// Private; Player doesn't have the item. (%s = item)
+	text_ITEM_MISSING = "You don't have a %s, recruit!"
+
+	// Private; Item needs a valid target. (%s = item)
+	text_ITEM_TARGET = "Who do you want to use that %s on, recruit? (" + cmd_PREFIX + cmd_USE + " <item> <nick>)"
+
+	// Private; Inventory. (%s = item list)
+	text_ITEM_LIST = "Your supplies: %s"
+
+	// Private; Empty inventory.
+	text_ITEM_NONE = "You don't have any supplies, recruit."
+
+	// Public; Player raised a shield. (%s = nickname)
+	text_ITEM_SHIELD = "%s raised a shield!"
+
+	// Private; Player already has a shield up.
+	text_ITEM_SHIELD_ACTIVE = "Your shield is already up, recruit!"
+
+	// Public; Shield blocked a throw. (first %s = shielded nickname; second %s = thrower)
+	text_ITEM_SHIELD_BLOCK = "The bomb bounces off %s's shield! %s is still holding it."
+
+	// Private; Time left hint. (first %d = lower bound; second %d = upper bound, in minutes)
+	text_ITEM_PEEK = "You peek at the bomb. It will explode somewhere between %d and %d minutes from now."
+
+	// Public; Bomb reversed. (first %s = nickname; second %s = previous holder)
+	text_ITEM_REVERSE = "%s sends the bomb right back to %s!"
+
+	// Private; Can't reverse right now.
+	text_ITEM_REVERSE_FAIL = "There's nobody to send the bomb back to."
+
+	// Public; Player froze another player. (first %s = nickname; second %s = target)
+	text_ITEM_FREEZE = "%s freezes %s! No throwing for a while."
+
+	// Public; Frozen player tries to throw. (%s = nickname)
+	text_ITEM_FROZEN = "%s is frozen and can't throw the bomb!"
+
+	//
+	// BETTING
+	//
+
+	// Private; Invalid bet.
+	text_BET_INVALID = "That's not how betting works, civilian. (" + cmd_PREFIX + cmd_BET + " <amount> <loser|fake|real|defused> [nick])"
+
+	// Private; Not enough money. (%d = balance)
+	text_BET_FUNDS = "You can't afford that, civilian! You only have %d."
+
+	// Private; Balance. (%d = balance)
+	text_BET_BALANCE = "Your balance: %d"
+
+	// Public; Bet placed. (%s = nickname; %d = amount; %s = kind)
+	text_BET_PLACED = "%s bets %d on a %s bomb!"
+
+	// Public; Bet on a loser placed. (%s = nickname; %d = amount; %s = player)
+	text_BET_PLACED_LOSER = "%s bets %d that %s will explode!"
+
+	// Public; Bets paid out. (%s = list of winners)
+	text_BET_WINNERS = "Bets paid out: %s"
+
+	//
+	// ADMIN
+	//
+
+	// Private; Sender may not use admin commands.
+	text_ADMIN_DENIED = "You're not in command here, recruit."
+
+	// Private; Admin command help.
+	text_ADMIN_HELP = "Usage: " + cmd_PREFIX + cmd_ADMIN + " <start|abort|kick <nick>|config set <name> <value>|unban <nick>|protect <nick>|unprotect <nick>|reveal|pending|cancel <nick>>"
+
+	// Private; No game is being played.
+	text_ADMIN_NOT_PLAYING = "There's no game running."
+
+	// Private; Nickname isn't playing. (%s = nickname)
+	text_ADMIN_NOT_PLAYER = "%s isn't playing."
+
+	// Public; Player was removed from the game. (%s = nickname)
+	text_ADMIN_REMOVED = "%s was dismissed from duty!"
+
+	// Private; Setting changed. (first %s = setting; second %s = value)
+	text_ADMIN_CONFIG_SET = "%s set to %s."
+
+	// Private; Setting could not be changed. (first %s = setting; second %s = error)
+	text_ADMIN_CONFIG_ERROR = "Can't change %s: %s."
+
+	// Private; Player was unbanned. (%s = nickname)
+	text_ADMIN_UNBANNED = "%s was unbanned."
+
+	// Public; Details of the last bomb. (first %s = real or fake; second %s = variant; %s = timer; %s = defuse details)
+	text_ADMIN_REVEAL = "Declassified: the last bomb was a %s %s bomb, set to explode after %s. It %s."
+
+	// Private; Bomb can't be revealed during a game.
+	text_ADMIN_REVEAL_RUNNING = "Classified! Wait until the game is over."
+
+	// Private; Protection changed. (%s = nickname; %t = protected)
+	text_ADMIN_PROTECTED = "%s protected: %t"
+
+	// Private; Scheduled action. (first %s = action; second %s = nickname; %s = time left)
+	text_ADMIN_PENDING = "%s %s in %s"
+
+	// Private; No scheduled actions.
+	text_ADMIN_PENDING_NONE = "Nothing scheduled."
+
+	// Private; Scheduled actions cancelled. (%d = number of actions; %s = nickname)
+	text_ADMIN_CANCELLED = "Cancelled %d scheduled actions for %s."
+
 	//
 	// COMMANDS
 	//
@@ -144,9 +400,54 @@ const (
 	// Cut a wire during defuse.
 	cmd_CUT = "cut"
 
+	// Enter a code during defuse.
+	cmd_CODE = "code"
+
+	// Answer a question during defuse.
+	cmd_ANSWER = "answer"
+
 	// Pick up the bomb when it's on the ground
 	cmd_PICK_UP = "pickup"
 
 	// Player list
 	cmd_PLAYER_LIST = "players"
+
+	// Use an item
+	cmd_USE = "use"
+
+	// List items
+	cmd_ITEMS = "items"
+
+	// Place a bet as spectator
+	cmd_BET = "bet"
+
+	// Show balance
+	cmd_BALANCE = "balance"
+
+	// Show rating
+	cmd_RATING = "rating"
+
+	// Never join games
+	cmd_OPT_OUT = "optout"
+
+	// Join games again after opting out
+	cmd_OPT_IN = "optin"
+
+	// Admin commands
+	cmd_ADMIN = "bomb"
+)
+
+// Admin commands, used as "!bomb <command>"
+const (
+	admin_START     = "start"     // Start a new game.
+	admin_ABORT     = "abort"     // Stop the game without results.
+	admin_KICK      = "kick"      // Remove a player from the game.
+	admin_CONFIG    = "config"    // Change settings.
+	admin_SET       = "set"       // Change a setting: config set <name> <value>.
+	admin_UNBAN     = "unban"     // Lift a ban right away.
+	admin_PROTECT   = "protect"   // Never kick or ban a player.
+	admin_UNPROTECT = "unprotect" // Allow kicking and banning a player again.
+	admin_REVEAL    = "reveal"    // Show details of the last bomb.
+	admin_PENDING   = "pending"   // List scheduled actions.
+	admin_CANCEL    = "cancel"    // Cancel scheduled actions for a player.
 )
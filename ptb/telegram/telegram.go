@@ -0,0 +1,245 @@
+// Package telegram connects Pass The Bomb to a Telegram group using the Bot
+// API. Commands can be typed as "!pass bob" or "/pass@botname @bob".
+//
+//	c := telegram.NewClient(token, chatID)
+//	g := ptb.NewGame(c)
+//	err := c.Run(g)
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Client tweaking
+const (
+	tweak_BASE_URL = "https://api.telegram.org" // Bot API server.
+)
+
+// Client is a Chat for a single Telegram group.
+// Players are known by their username, or first name if they don't have one,
+// and identified by their user ID.
+type Client struct {
+	BaseURL string       // Bot API server, like https://api.telegram.org.
+	Token   string       // Bot token.
+	ChatID  int64        // Group the game is played in.
+	HTTP    *http.Client // Client used for requests.
+
+	Username string // Username of the bot, set by Run if empty.
+	UserID   int64  // User ID of the bot, set by Run if zero.
+
+	mutex  sync.Mutex
+	users  map[string]int64 // User IDs for nicknames.
+	nicks  map[int64]string // Nicknames for user IDs.
+	banned map[string]int64 // User IDs of banned nicknames.
+}
+
+// NewClient returns a client for given group.
+func NewClient(token string, chatID int64) *Client {
+	return &Client{
+		BaseURL: tweak_BASE_URL,
+		Token:   token,
+		ChatID:  chatID,
+		HTTP:    http.DefaultClient,
+		users:   make(map[string]int64),
+		nicks:   make(map[int64]string),
+		banned:  make(map[string]int64),
+	}
+}
+
+// Error is returned by the Bot API when a request fails.
+type Error struct {
+	Code        int    `json:"error_code"`
+	Description string `json:"description"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("telegram: %s (%d)", e.Description, e.Code)
+}
+
+// response wraps every Bot API result.
+type response struct {
+	OK     bool            `json:"ok"`
+	Result json.RawMessage `json:"result"`
+	Error
+}
+
+// call invokes a Bot API method and decodes the result into out, if not nil.
+func (c *Client) call(method string, params, out interface{}) error {
+
+	if params == nil {
+		params = struct{}{}
+	}
+
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	u := strings.TrimRight(c.BaseURL, "/") + "/bot" + c.Token + "/" + method
+
+	resp, err := c.HTTP.Post(u, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var r response
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+
+	if !r.OK {
+		return &r.Error
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal(r.Result, out)
+}
+
+// userID returns the user ID for given nickname, or zero if unknown.
+func (c *Client) userID(nick string) int64 {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if id, known := c.users[strings.ToLower(nick)]; known {
+		return id
+	}
+
+	return c.banned[strings.ToLower(nick)]
+}
+
+// send sends a message to given chat.
+func (c *Client) send(chatID int64, text string) error {
+	return c.call("sendMessage", map[string]interface{}{"chat_id": chatID, "text": text}, nil)
+}
+
+// Public sends a message to the group.
+func (c *Client) Public(text string) {
+	c.send(c.ChatID, text)
+}
+
+// Private sends a message to given player, this only works if they started
+// a conversation with the bot.
+func (c *Client) Private(nick, text string) {
+	if id := c.userID(nick); id != 0 {
+		c.send(id, text)
+	}
+}
+
+// restrict bans or unbans a user, unbanning a member doesn't kick them.
+func (c *Client) restrict(method string, id int64) error {
+	return c.call(method, map[string]interface{}{"chat_id": c.ChatID, "user_id": id, "only_if_banned": method == "unbanChatMember"}, nil)
+}
+
+// Kick removes a player from the group, they can join again right away.
+// Banned players are gone already, unbanning them would lift the ban.
+func (c *Client) Kick(nick, reason string) {
+
+	c.mutex.Lock()
+	_, banned := c.banned[strings.ToLower(nick)]
+	c.mutex.Unlock()
+
+	id := c.userID(nick)
+	if id == 0 || banned {
+		return
+	}
+
+	if c.restrict("banChatMember", id) == nil {
+		c.restrict("unbanChatMember", id)
+	}
+
+}
+
+// Ban bans a player from the group.
+func (c *Client) Ban(nick string) bool {
+
+	id := c.userID(nick)
+	if id == 0 || c.restrict("banChatMember", id) != nil {
+		return false
+	}
+
+	// Remember who this was, banned players leave the group.
+	c.mutex.Lock()
+	c.banned[strings.ToLower(nick)] = id
+	c.mutex.Unlock()
+
+	return true
+}
+
+// UnBan lifts a ban.
+func (c *Client) UnBan(nick string) {
+
+	if id := c.userID(nick); id != 0 {
+		c.restrict("unbanChatMember", id)
+	}
+
+	c.mutex.Lock()
+	delete(c.banned, strings.ToLower(nick))
+	c.mutex.Unlock()
+
+}
+
+// member is a member of the group.
+type member struct {
+	Status             string `json:"status"`
+	CanRestrictMembers bool   `json:"can_restrict_members"`
+}
+
+// member fetches the membership of given user.
+func (c *Client) member(id int64) (*member, error) {
+	m := new(member)
+	return m, c.call("getChatMember", map[string]interface{}{"chat_id": c.ChatID, "user_id": id}, m)
+}
+
+// IsOperator returns true if the bot may ban players.
+func (c *Client) IsOperator() bool {
+
+	m, err := c.member(c.UserID)
+	if err != nil {
+		return false
+	}
+
+	return m.Status == "creator" || (m.Status == "administrator" && m.CanRestrictMembers)
+}
+
+// IsAdmin returns true if given player is an administrator of the group.
+func (c *Client) IsAdmin(nick string) bool {
+
+	id := c.userID(nick)
+	if id == 0 {
+		return false
+	}
+
+	m, err := c.member(id)
+	if err != nil {
+		return false
+	}
+
+	return m.Status == "creator" || m.Status == "administrator"
+}
+
+// Identity returns the user ID for given nickname.
+func (c *Client) Identity(nick string) string {
+
+	if id := c.userID(nick); id != 0 {
+		return strconv.FormatInt(id, 10)
+	}
+
+	return ""
+}
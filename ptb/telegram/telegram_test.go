@@ -0,0 +1,351 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sorcix/passthebomb/ptb"
+)
+
+// call is a Bot API request received by the fake server.
+type call struct {
+	Method string
+	Params map[string]interface{}
+}
+
+// botAPI is a fake Bot API server. getUpdates returns the updates pushed by
+// the test, and ends Run once there are no more.
+type botAPI struct {
+	*httptest.Server
+	updates chan string
+	calls   chan call
+	quit    chan bool
+
+	mutex   sync.Mutex
+	members map[int64]string // Results of getChatMember.
+}
+
+func newBotAPI(t *testing.T) *botAPI {
+
+	b := &botAPI{
+		updates: make(chan string),
+		calls:   make(chan call, 100),
+		quit:    make(chan bool),
+		members: make(map[int64]string),
+	}
+
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		method := strings.TrimPrefix(r.URL.Path, "/bottoken/")
+		if method == r.URL.Path {
+			t.Errorf("%s: missing token", r.URL.Path)
+		}
+
+		var params map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&params)
+
+		result := "true"
+
+		switch method {
+
+		case "getMe":
+			result = `{"id":1,"is_bot":true,"first_name":"Bomb","username":"ptbbot"}`
+
+		case "getUpdates":
+			result = ""
+			select {
+			case result = <-b.updates:
+			case <-b.quit:
+			}
+			if result == "" {
+				w.Write([]byte(`{"ok":false,"error_code":409,"description":"Conflict"}`))
+				return
+			}
+
+		case "getChatMember":
+			b.mutex.Lock()
+			result = b.members[int64(params["user_id"].(float64))]
+			b.mutex.Unlock()
+
+		default:
+			if params["user_id"] == float64(666) {
+				w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: not enough rights"}`))
+				return
+			}
+			b.calls <- call{method, params}
+
+		}
+
+		w.Write([]byte(`{"ok":true,"result":` + result + `}`))
+
+	}))
+
+	return b
+}
+
+// Close stops the server, polls that are waiting for updates fail.
+func (b *botAPI) Close() {
+	close(b.quit)
+	b.Server.Close()
+}
+
+// client returns a client for group -100 using the fake server.
+func (b *botAPI) client() *Client {
+	c := NewClient("token", -100)
+	c.BaseURL = b.URL + "/"
+	return c
+}
+
+// member sets the result of getChatMember for given user.
+func (b *botAPI) member(id int64, status string, restrict bool) {
+	b.mutex.Lock()
+	b.members[id] = fmt.Sprintf(`{"status":%q,"can_restrict_members":%v}`, status, restrict)
+	b.mutex.Unlock()
+}
+
+// expect waits for a call to given method with given parameter.
+// Other calls are skipped.
+func (b *botAPI) expect(t *testing.T, method, key string, value interface{}) call {
+
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case c := <-b.calls:
+			if c.Method != method {
+				continue
+			}
+			if s, ok := value.(string); ok && strings.Contains(fmt.Sprint(c.Params[key]), s) {
+				return c
+			}
+			if c.Params[key] == value {
+				return c
+			}
+		case <-timeout:
+			t.Fatalf("no %s with %s %v", method, key, value)
+		}
+	}
+}
+
+// none fails if there are calls left.
+func (b *botAPI) none(t *testing.T) {
+
+	t.Helper()
+
+	select {
+	case c := <-b.calls:
+		t.Fatalf("unexpected %s %v", c.Method, c.Params)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// updateJSON returns updates with a message in group -100.
+func updateJSON(id int, from, text string) string {
+	return fmt.Sprintf(`[{"update_id":%d,"message":{"from":%s,"chat":{"id":-100},"text":%q}}]`, id, from, text)
+}
+
+func TestRun(t *testing.T) {
+
+	b := newBotAPI(t)
+	defer b.Close()
+
+	c := b.client()
+	g := ptb.NewGame(c)
+	g.Configure("outbound_burst", "0")
+
+	done := make(chan error)
+	go func() {
+		done <- c.Run(g)
+	}()
+
+	bob := `{"id":2,"first_name":"Bob","username":"bob"}`
+
+	// Learn about bob before the game starts.
+	b.updates <- updateJSON(1, bob, "hi")
+
+	go g.Start()
+	b.expect(t, "sendMessage", "text", "Attentiooooon")
+
+	// Commands for this bot are passed to the game.
+	b.updates <- updateJSON(2, bob, "/join@ptbbot")
+	b.expect(t, "sendMessage", "text", "enlisted")
+
+	// Username changes are renames.
+	b.updates <- updateJSON(3, `{"id":2,"first_name":"Bob","username":"bobby"}`, "hello")
+	b.expect(t, "sendMessage", "text", "bob is acting like a complete asshole and is now known as bobby")
+
+	// Leaving the group leaves the game.
+	b.updates <- `[{"update_id":4,"message":{"from":{"id":2},"chat":{"id":-100},"left_chat_member":{"id":2,"first_name":"Bob","username":"bobby"}}}]`
+	b.expect(t, "sendMessage", "text", "bobby has gone AWOL")
+
+	close(b.updates)
+
+	if err := <-done; err == nil {
+		t.Fatal("Run returned without error")
+	}
+
+	if c.UserID != 1 || c.Username != "ptbbot" {
+		t.Fatalf("bot is %d %s", c.UserID, c.Username)
+	}
+
+}
+
+func TestSend(t *testing.T) {
+
+	b := newBotAPI(t)
+	defer b.Close()
+
+	c := b.client()
+	c.nick(nil, user{ID: 2, Username: "bob"})
+
+	c.Public("hello")
+	b.expect(t, "sendMessage", "chat_id", float64(-100))
+
+	c.Private("Bob", "psst")
+	if r := b.expect(t, "sendMessage", "chat_id", float64(2)); r.Params["text"] != "psst" {
+		t.Fatalf("sent %v", r.Params["text"])
+	}
+
+	// Can't send to someone we don't know.
+	c.Private("alice", "psst")
+	b.none(t)
+
+}
+
+func TestKickBan(t *testing.T) {
+
+	b := newBotAPI(t)
+	defer b.Close()
+
+	c := b.client()
+	c.nick(nil, user{ID: 2, Username: "bob"})
+	c.nick(nil, user{ID: 3, Username: "carol"})
+	c.nick(nil, user{ID: 666, Username: "mallory"})
+
+	// Kicking is a ban that's lifted right away.
+	c.Kick("carol", "boom")
+	b.expect(t, "banChatMember", "user_id", float64(3))
+	if r := b.expect(t, "unbanChatMember", "user_id", float64(3)); r.Params["only_if_banned"] != true {
+		t.Fatal("unban might kick")
+	}
+
+	if !c.Ban("bob") {
+		t.Fatal("ban failed")
+	}
+	b.expect(t, "banChatMember", "user_id", float64(2))
+
+	// Players are kicked after a ban, that shouldn't lift it.
+	c.Kick("bob", "boom")
+	b.none(t)
+
+	// Banned players leave the group, but can still be unbanned by name.
+	c.mutex.Lock()
+	delete(c.users, "bob")
+	delete(c.nicks, 2)
+	c.mutex.Unlock()
+
+	c.UnBan("bob")
+	b.expect(t, "unbanChatMember", "user_id", float64(2))
+
+	if c.Ban("mallory") {
+		t.Fatal("ban succeeded without rights")
+	}
+
+	if c.Ban("nobody") {
+		t.Fatal("banned an unknown user")
+	}
+
+}
+
+func TestMember(t *testing.T) {
+
+	b := newBotAPI(t)
+	defer b.Close()
+
+	c := b.client()
+	c.UserID = 1
+	c.nick(nil, user{ID: 2, Username: "bob"})
+
+	tests := []struct {
+		status   string
+		restrict bool
+		operator bool
+		admin    bool
+	}{
+		{"creator", false, true, true},
+		{"administrator", true, true, true},
+		{"administrator", false, false, true},
+		{"member", true, false, false},
+		{"left", false, false, false},
+	}
+
+	for _, test := range tests {
+
+		b.member(1, test.status, test.restrict)
+		b.member(2, test.status, test.restrict)
+
+		if c.IsOperator() != test.operator {
+			t.Errorf("%s: operator should be %v", test.status, test.operator)
+		}
+
+		if c.IsAdmin("bob") != test.admin {
+			t.Errorf("%s: admin should be %v", test.status, test.admin)
+		}
+
+	}
+
+	if c.IsAdmin("nobody") {
+		t.Error("unknown user is an admin")
+	}
+
+}
+
+func TestCommand(t *testing.T) {
+
+	c := NewClient("token", -100)
+	c.Username = "ptbbot"
+	c.nick(nil, user{ID: 5, FirstName: "Carol Ann"})
+
+	carol := &user{ID: 5, FirstName: "Carol Ann"}
+
+	tests := []struct {
+		text     string
+		entities []entity
+		out      string
+		ok       bool
+	}{
+		{"/pass@ptbbot @bob", []entity{{Type: "bot_command", Offset: 0, Length: 12}, {Type: "mention", Offset: 13, Length: 4}}, "!pass bob", true},
+		{"/pass@PTBbot bob", nil, "!pass bob", true},
+		{"/pass bob", nil, "!pass bob", true},
+		{"/pass@otherbot bob", nil, "", false},
+		{"!pass bob", nil, "!pass bob", true},
+		{"the wire is red", nil, "the wire is red", true},
+		// Players without a username are mentioned by user.
+		{"/pass Carol", []entity{{Type: "text_mention", Offset: 6, Length: 5, User: carol}}, "!pass Carol_Ann", true},
+		// Offsets count UTF-16 code units, emoji take two.
+		{"💣 @bob and @alice", []entity{{Type: "mention", Offset: 3, Length: 4}, {Type: "mention", Offset: 12, Length: 6}}, "💣 bob and alice", true},
+		// Broken entities are ignored.
+		{"@bob", []entity{{Type: "mention", Offset: 0, Length: 0}}, "@bob", true},
+		{"@bob", []entity{{Type: "mention", Offset: 2, Length: 4}}, "@bob", true},
+		{"@bob", []entity{{Type: "text_mention", Offset: 0, Length: 4}}, "@bob", true},
+	}
+
+	for _, test := range tests {
+
+		out, ok := c.command(&message{Text: test.text, Entities: test.entities})
+
+		if out != test.out || ok != test.ok {
+			t.Errorf("%q: got %q %v, want %q %v", test.text, out, ok, test.out, test.ok)
+		}
+
+	}
+
+}
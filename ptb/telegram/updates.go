@@ -0,0 +1,246 @@
+package telegram
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/sorcix/passthebomb/ptb"
+)
+
+// Long polling timeout in seconds.
+const tweak_POLL_TIMEOUT = 30
+
+// Prefix for Telegram style commands.
+const cmd_SLASH = "/"
+
+// user is a Telegram user.
+type user struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username"`
+}
+
+// entity marks a special part of a message, like a mention.
+// Offsets are counted in UTF-16 code units.
+type entity struct {
+	Type   string `json:"type"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	User   *user  `json:"user"`
+}
+
+// message is a message sent to the group.
+type message struct {
+	From *user `json:"from"`
+	Chat struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Text     string   `json:"text"`
+	Entities []entity `json:"entities"`
+	Joined   []user   `json:"new_chat_members"`
+	Left     *user    `json:"left_chat_member"`
+}
+
+// update is received by polling the Bot API.
+type update struct {
+	ID      int64    `json:"update_id"`
+	Message *message `json:"message"`
+}
+
+// Run polls the Bot API and passes messages in the group to the game.
+// Members leaving are mapped to Leave, username changes to Rename.
+// Run blocks until a request fails.
+func (c *Client) Run(g *ptb.Game) error {
+
+	if c.UserID == 0 || c.Username == "" {
+		var me user
+		if err := c.call("getMe", nil, &me); err != nil {
+			return err
+		}
+		c.UserID, c.Username = me.ID, me.Username
+	}
+
+	var offset int64
+
+	for {
+
+		var updates []update
+
+		params := map[string]interface{}{
+			"offset":          offset,
+			"timeout":         tweak_POLL_TIMEOUT,
+			"allowed_updates": []string{"message"},
+		}
+
+		if err := c.call("getUpdates", params, &updates); err != nil {
+			return err
+		}
+
+		for _, u := range updates {
+			offset = u.ID + 1
+			if u.Message != nil && u.Message.Chat.ID == c.ChatID {
+				c.message(g, u.Message)
+			}
+		}
+
+	}
+
+}
+
+// message handles a message in the group.
+func (c *Client) message(g *ptb.Game, m *message) {
+
+	for _, u := range m.Joined {
+		c.nick(g, u)
+	}
+
+	if m.Left != nil {
+		c.leave(g, m.Left.ID)
+		return
+	}
+
+	if m.From == nil || m.From.IsBot || m.Text == "" {
+		return
+	}
+
+	nick := c.nick(g, *m.From)
+
+	if text, ok := c.command(m); ok {
+		g.Decode(nick, text)
+	}
+
+}
+
+// command rewrites a Telegram command like "/pass@botname @bob" to the
+// game's syntax, "!pass bob". Mentions are replaced by nicknames, other
+// messages are passed as they are so players can share hints.
+// Returns false for commands meant for another bot.
+func (c *Client) command(m *message) (string, bool) {
+
+	text := c.mentions(m)
+
+	if !strings.HasPrefix(text, cmd_SLASH) {
+		return text, true
+	}
+
+	args := strings.SplitN(text[len(cmd_SLASH):], " ", 2)
+
+	if i := strings.Index(args[0], "@"); i >= 0 {
+		if !strings.EqualFold(args[0][i+1:], c.Username) {
+			return "", false
+		}
+		args[0] = args[0][:i]
+	}
+
+	return "!" + strings.Join(args, " "), true
+}
+
+// mentions replaces mentions in a message by the nicknames of the players.
+func (c *Client) mentions(m *message) string {
+
+	text := utf16.Encode([]rune(m.Text))
+	out := make([]uint16, 0, len(text))
+	last := 0
+
+	for _, e := range m.Entities {
+
+		if e.Length <= 0 || e.Offset < last || e.Offset+e.Length > len(text) {
+			continue
+		}
+
+		var nick string
+
+		switch e.Type {
+		case "mention":
+			// Usernames are nicknames, without the at sign.
+			nick = string(utf16.Decode(text[e.Offset+1 : e.Offset+e.Length]))
+		case "text_mention":
+			if e.User == nil {
+				continue
+			}
+			nick = c.name(*e.User)
+		default:
+			continue
+		}
+
+		out = append(out, text[last:e.Offset]...)
+		out = append(out, utf16.Encode([]rune(nick))...)
+		last = e.Offset + e.Length
+
+	}
+
+	out = append(out, text[last:]...)
+
+	return string(utf16.Decode(out))
+}
+
+// nick returns the nickname of a user, and tells the game if it changed.
+func (c *Client) nick(g *ptb.Game, u user) string {
+
+	nick := c.name(u)
+
+	c.mutex.Lock()
+	old, known := c.nicks[u.ID]
+	c.mutex.Unlock()
+
+	if known && old == nick {
+		return nick
+	}
+
+	// The game might know this player by their old nickname.
+	if known {
+		g.Rename(old, nick)
+	}
+
+	c.mutex.Lock()
+	delete(c.users, strings.ToLower(old))
+	c.users[strings.ToLower(nick)] = u.ID
+	c.nicks[u.ID] = nick
+	c.mutex.Unlock()
+
+	return nick
+}
+
+// name returns the nickname for a user: their username, or first name if
+// they don't have one. Spaces become underscores so players can type it,
+// first names used by someone else get the user ID appended.
+func (c *Client) name(u user) string {
+
+	if u.Username != "" {
+		return u.Username
+	}
+
+	name := strings.Join(strings.Fields(u.FirstName), "_")
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if id, taken := c.users[strings.ToLower(name)]; name == "" || (taken && id != u.ID) {
+		return name + strconv.FormatInt(u.ID, 10)
+	}
+
+	return name
+}
+
+// leave tells the game a user left the group, and forgets about them.
+func (c *Client) leave(g *ptb.Game, id int64) {
+
+	c.mutex.Lock()
+	nick, known := c.nicks[id]
+	c.mutex.Unlock()
+
+	if !known {
+		return
+	}
+
+	// Tell the game first, it might know this player by their user ID.
+	g.Leave(nick)
+
+	c.mutex.Lock()
+	delete(c.nicks, id)
+	delete(c.users, strings.ToLower(nick))
+	c.mutex.Unlock()
+
+}
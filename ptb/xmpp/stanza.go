@@ -0,0 +1,119 @@
+package xmpp
+
+import (
+	"encoding/xml"
+)
+
+// Namespace for STARTTLS, other namespaces are in the struct tags below.
+const ns_TLS = "urn:ietf:params:xml:ns:xmpp-tls"
+
+// MUC status codes
+const (
+	status_SELF       = "110" // Presence refers to ourselves.
+	status_NICKCHANGE = "303" // Occupant changed nickname.
+)
+
+// features lists what the server offers after opening a stream.
+type features struct {
+	StartTLS   *struct{}   `xml:"urn:ietf:params:xml:ns:xmpp-tls starttls"`
+	Mechanisms *mechanisms `xml:"urn:ietf:params:xml:ns:xmpp-sasl mechanisms"`
+	Bind       *struct{}   `xml:"urn:ietf:params:xml:ns:xmpp-bind bind"`
+}
+
+// mechanisms lists the SASL mechanisms offered by the server.
+type mechanisms struct {
+	Mechanism []string `xml:"mechanism"`
+}
+
+// auth starts SASL authentication.
+type auth struct {
+	XMLName   xml.Name `xml:"urn:ietf:params:xml:ns:xmpp-sasl auth"`
+	Mechanism string   `xml:"mechanism,attr"`
+	Value     string   `xml:",chardata"`
+}
+
+// bind asks the server for a resource.
+type bind struct {
+	XMLName  xml.Name `xml:"urn:ietf:params:xml:ns:xmpp-bind bind"`
+	Resource string   `xml:"resource,omitempty"`
+	JID      string   `xml:"jid,omitempty"`
+}
+
+// message is a chat message.
+type message struct {
+	XMLName xml.Name  `xml:"message"`
+	To      string    `xml:"to,attr,omitempty"`
+	From    string    `xml:"from,attr,omitempty"`
+	Type    string    `xml:"type,attr,omitempty"`
+	Body    string    `xml:"body,omitempty"`
+	Delay   *struct{} `xml:"urn:xmpp:delay delay"`
+}
+
+// presence tells others we're around, or that someone left.
+type presence struct {
+	XMLName xml.Name `xml:"presence"`
+	To      string   `xml:"to,attr,omitempty"`
+	From    string   `xml:"from,attr,omitempty"`
+	Type    string   `xml:"type,attr,omitempty"`
+	Join    *join    `xml:"http://jabber.org/protocol/muc x"`
+	User    *mucUser `xml:"http://jabber.org/protocol/muc#user x"`
+}
+
+// join is sent to enter a room.
+type join struct {
+	XMLName xml.Name `xml:"http://jabber.org/protocol/muc x"`
+	History history  `xml:"history"`
+}
+
+// history limits the messages a room sends when joining.
+type history struct {
+	MaxStanzas int `xml:"maxstanzas,attr"`
+}
+
+// mucUser describes an occupant of a room.
+type mucUser struct {
+	Item   item     `xml:"item"`
+	Status []status `xml:"status"`
+}
+
+// item holds the role and affiliation of an occupant.
+type item struct {
+	JID         string `xml:"jid,attr,omitempty"`
+	Nick        string `xml:"nick,attr,omitempty"`
+	Role        string `xml:"role,attr,omitempty"`
+	Affiliation string `xml:"affiliation,attr,omitempty"`
+	Reason      string `xml:"reason,omitempty"`
+}
+
+// status is a MUC status code.
+type status struct {
+	Code string `xml:"code,attr"`
+}
+
+// iq is a request or response.
+type iq struct {
+	XMLName xml.Name  `xml:"iq"`
+	ID      string    `xml:"id,attr"`
+	To      string    `xml:"to,attr,omitempty"`
+	From    string    `xml:"from,attr,omitempty"`
+	Type    string    `xml:"type,attr"`
+	Bind    *bind     `xml:"urn:ietf:params:xml:ns:xmpp-bind bind"`
+	Admin   *admin    `xml:"http://jabber.org/protocol/muc#admin query"`
+	Ping    *struct{} `xml:"urn:xmpp:ping ping"`
+	Error   *iqError  `xml:"error"`
+}
+
+// admin changes roles and affiliations in a room.
+type admin struct {
+	XMLName xml.Name `xml:"http://jabber.org/protocol/muc#admin query"`
+	Item    item     `xml:"item"`
+}
+
+// iqError is returned for requests we don't support.
+type iqError struct {
+	Type      string    `xml:"type,attr"`
+	Condition condition `xml:"urn:ietf:params:xml:ns:xmpp-stanzas feature-not-implemented"`
+}
+
+// condition is an empty error condition element.
+type condition struct{}
@@ -0,0 +1,392 @@
+package xmpp
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/sorcix/passthebomb/ptb"
+)
+
+// Stanzas waiting for the game, the connection is read while the game is busy
+// so responses to our own requests don't get stuck.
+const tweak_QUEUE = 64
+
+// Run connects to the server, joins the room and passes messages to the game.
+// Nickname changes are mapped to Rename, occupants leaving to Leave.
+// Run blocks until the connection fails.
+func (c *Client) Run(g *ptb.Game) error {
+
+	if err := c.connect(); err != nil {
+		return err
+	}
+
+	defer c.close()
+
+	stanzas := make(chan interface{}, tweak_QUEUE)
+	defer close(stanzas)
+
+	go func() {
+		for s := range stanzas {
+			c.handle(g, s)
+		}
+	}()
+
+	// Enter the room, without history.
+	if err := c.send(&presence{To: c.Room + "/" + c.Nick, Join: new(join)}); err != nil {
+		return err
+	}
+
+	for {
+
+		s, err := c.next()
+		if err != nil {
+			return err
+		}
+
+		switch s := s.(type) {
+
+		case *iq:
+			c.iq(s)
+
+		case *message, *presence:
+			stanzas <- s
+
+		}
+
+	}
+
+}
+
+// connect opens a stream, authenticates and binds a resource.
+func (c *Client) connect() error {
+
+	server := c.Server
+	if server == "" {
+		server = net.JoinHostPort(domain(c.JID), tweak_PORT)
+	}
+
+	conn, err := net.Dial("tcp", server)
+	if err != nil {
+		return err
+	}
+
+	c.setConn(conn)
+
+	f, err := c.open()
+	if err != nil {
+		return err
+	}
+
+	// Never send the password in plain text, unless asked to.
+	if f.StartTLS == nil && !c.Insecure {
+		return errors.New("xmpp: server doesn't support STARTTLS, set Insecure to log in without it")
+	}
+
+	// Encrypt the connection.
+	if f.StartTLS != nil {
+
+		if err := c.raw("<starttls xmlns='" + ns_TLS + "'/>"); err != nil {
+			return err
+		}
+
+		if e, err := c.element(); err != nil {
+			return err
+		} else if e.Name.Local != "proceed" {
+			return errors.New("xmpp: starttls refused")
+		}
+
+		config := c.TLS
+		if config == nil {
+			config = &tls.Config{ServerName: domain(c.JID)}
+		}
+
+		tc := tls.Client(conn, config)
+		if err := tc.Handshake(); err != nil {
+			return err
+		}
+
+		c.setConn(tc)
+
+		if f, err = c.open(); err != nil {
+			return err
+		}
+
+	}
+
+	if !f.plain() {
+		return errors.New("xmpp: server doesn't support PLAIN authentication")
+	}
+
+	credentials := "\x00" + local(c.JID) + "\x00" + c.Password
+	if err := c.send(&auth{Mechanism: "PLAIN", Value: base64.StdEncoding.EncodeToString([]byte(credentials))}); err != nil {
+		return err
+	}
+
+	if e, err := c.element(); err != nil {
+		return err
+	} else if e.Name.Local != "success" {
+		return errors.New("xmpp: authentication failed")
+	}
+
+	if _, err = c.open(); err != nil {
+		return err
+	}
+
+	// Bind a resource, nobody is reading responses yet.
+	if err := c.send(&iq{ID: "bind", Type: "set", Bind: &bind{Resource: tweak_RESOURCE}}); err != nil {
+		return err
+	}
+
+	s, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	if r, ok := s.(*iq); !ok || r.Type != "result" || r.Bind == nil {
+		return errors.New("xmpp: resource binding failed")
+	}
+
+	c.JID = s.(*iq).Bind.JID
+
+	return nil
+}
+
+// plain returns true if the server offers PLAIN authentication.
+func (f *features) plain() bool {
+
+	if f.Mechanisms == nil {
+		return false
+	}
+
+	for _, m := range f.Mechanisms.Mechanism {
+		if m == "PLAIN" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setConn switches to another connection, like after STARTTLS.
+func (c *Client) setConn(conn net.Conn) {
+
+	c.write.Lock()
+	defer c.write.Unlock()
+
+	c.conn = conn
+	c.decoder = xml.NewDecoder(conn)
+
+}
+
+// close closes the connection.
+func (c *Client) close() {
+
+	c.write.Lock()
+	defer c.write.Unlock()
+
+	if c.conn != nil {
+		c.conn.Write([]byte("</stream:stream>"))
+		c.conn.Close()
+		c.conn = nil
+	}
+
+}
+
+// raw writes a string to the server.
+func (c *Client) raw(s string) error {
+
+	c.write.Lock()
+	defer c.write.Unlock()
+
+	_, err := io.WriteString(c.conn, s)
+
+	return err
+}
+
+// open starts a new stream and returns the features offered by the server.
+func (c *Client) open() (*features, error) {
+
+	header := fmt.Sprintf("<?xml version='1.0'?><stream:stream to='%s' xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>", domain(c.JID))
+
+	if err := c.raw(header); err != nil {
+		return nil, err
+	}
+
+	// Restart the decoder, a new stream is a new document.
+	c.decoder = xml.NewDecoder(c.conn)
+
+	e, err := c.element()
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Name.Local != "stream" {
+		return nil, errors.New("xmpp: expected stream, got " + e.Name.Local)
+	}
+
+	if e, err = c.element(); err != nil {
+		return nil, err
+	}
+
+	if e.Name.Local != "features" {
+		return nil, errors.New("xmpp: expected features, got " + e.Name.Local)
+	}
+
+	f := new(features)
+
+	return f, c.decoder.DecodeElement(f, &e)
+}
+
+// element returns the next element, or io.EOF when the stream ends.
+func (c *Client) element() (xml.StartElement, error) {
+
+	for {
+
+		t, err := c.decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+
+		switch t := t.(type) {
+
+		case xml.StartElement:
+			return t, nil
+
+		case xml.EndElement:
+			return xml.StartElement{}, io.EOF
+
+		}
+
+	}
+
+}
+
+// next returns the next stanza, elements we don't know are skipped.
+func (c *Client) next() (interface{}, error) {
+
+	for {
+
+		e, err := c.element()
+		if err != nil {
+			return nil, err
+		}
+
+		var s interface{}
+
+		switch e.Name.Local {
+		case "message":
+			s = new(message)
+		case "presence":
+			s = new(presence)
+		case "iq":
+			s = new(iq)
+		case "error":
+			return nil, errors.New("xmpp: stream error")
+		default:
+			if err := c.decoder.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		return s, c.decoder.DecodeElement(s, &e)
+	}
+
+}
+
+// iq handles responses to our requests, and answers pings.
+func (c *Client) iq(s *iq) {
+
+	switch s.Type {
+
+	case "result", "error":
+		c.mutex.Lock()
+		resp, waiting := c.pending[s.ID]
+		c.mutex.Unlock()
+
+		if waiting {
+			resp <- s
+		}
+
+	case "get", "set":
+		r := &iq{ID: s.ID, To: s.From, Type: "result"}
+		if s.Ping == nil {
+			r.Type = "error"
+			r.Error = &iqError{Type: "cancel"}
+		}
+		c.send(r)
+
+	}
+
+}
+
+// handle passes messages and presence in the room to the game.
+func (c *Client) handle(g *ptb.Game, s interface{}) {
+
+	switch s := s.(type) {
+
+	case *message:
+		nick := resource(s.From)
+
+		// Skip history, our own messages and private messages.
+		if s.Type != "groupchat" || s.Delay != nil || s.Body == "" || bare(s.From) != c.Room || nick == "" || nick == c.nick() {
+			return
+		}
+
+		g.Decode(nick, s.Body)
+
+	case *presence:
+		if bare(s.From) == c.Room && s.User != nil {
+			c.presence(g, resource(s.From), s)
+		}
+
+	}
+
+}
+
+// presence keeps track of occupants joining, leaving and changing nicknames.
+func (c *Client) presence(g *ptb.Game, nick string, s *presence) {
+
+	codes := make(map[string]bool)
+	for _, st := range s.User.Status {
+		codes[st.Code] = true
+	}
+
+	// The room might change our nickname.
+	if codes[status_SELF] && s.Type == "" {
+		c.mutex.Lock()
+		c.Nick = nick
+		c.mutex.Unlock()
+	}
+
+	if s.Type != "unavailable" {
+		i := s.User.Item
+		c.mutex.Lock()
+		c.occupants[nick] = &i
+		c.mutex.Unlock()
+		return
+	}
+
+	// Tell the game before forgetting the occupant, the game might know
+	// them by their JID.
+	if codes[status_NICKCHANGE] && s.User.Item.Nick != "" {
+		g.Rename(nick, s.User.Item.Nick)
+		c.mutex.Lock()
+		c.occupants[s.User.Item.Nick] = c.occupants[nick]
+		delete(c.occupants, nick)
+		c.mutex.Unlock()
+		return
+	}
+
+	g.Leave(nick)
+
+	c.mutex.Lock()
+	delete(c.occupants, nick)
+	c.mutex.Unlock()
+
+}
@@ -0,0 +1,253 @@
+// Package xmpp connects Pass The Bomb to an XMPP multi-user chat room
+// (XEP-0045).
+//
+//	c := xmpp.NewClient("bot@example.org", password, "room@conference.example.org", "bomb")
+//	g := ptb.NewGame(c)
+//	err := c.Run(g)
+package xmpp
+
+import (
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client tweaking
+const (
+	tweak_IQ_TIMEOUT = 10    // Time to wait for a response from the server in seconds.
+	tweak_RESOURCE   = "ptb" // Resource to bind.
+	tweak_PORT       = "5222"
+)
+
+// Client is a Chat for a single multi-user chat room.
+// Players are known by their room nickname, and identified by their real JID
+// if the room shares it with us.
+type Client struct {
+	JID      string      // Account of the bot, like bot@example.org.
+	Password string      // Password of the bot account.
+	Room     string      // Room JID, like room@conference.example.org.
+	Nick     string      // Nickname of the bot in the room.
+	Server   string      // Server address, defaults to the JID's domain on port 5222.
+	TLS      *tls.Config // TLS settings used when the server offers STARTTLS.
+	Insecure bool        // Send the password without TLS if the server doesn't offer STARTTLS.
+
+	conn    net.Conn
+	decoder *xml.Decoder
+	write   sync.Mutex
+
+	mutex     sync.Mutex
+	id        int
+	pending   map[string]chan *iq
+	occupants map[string]*item  // Occupants by nickname.
+	banned    map[string]string // Real JIDs of banned nicknames.
+}
+
+// NewClient returns a client for given room.
+func NewClient(jid, password, room, nick string) *Client {
+	return &Client{
+		JID:       jid,
+		Password:  password,
+		Room:      room,
+		Nick:      nick,
+		pending:   make(map[string]chan *iq),
+		occupants: make(map[string]*item),
+		banned:    make(map[string]string),
+	}
+}
+
+// send writes a stanza to the server.
+func (c *Client) send(v interface{}) error {
+
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.write.Lock()
+	defer c.write.Unlock()
+
+	if c.conn == nil {
+		return errors.New("xmpp: not connected")
+	}
+
+	_, err = c.conn.Write(b)
+
+	return err
+}
+
+// request sends an iq and waits for the response.
+func (c *Client) request(req *iq) (*iq, error) {
+
+	c.mutex.Lock()
+	c.id++
+	req.ID = "ptb" + strconv.Itoa(c.id)
+	resp := make(chan *iq, 1)
+	c.pending[req.ID] = resp
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pending, req.ID)
+		c.mutex.Unlock()
+	}()
+
+	if err := c.send(req); err != nil {
+		return nil, err
+	}
+
+	select {
+
+	case r := <-resp:
+		if r.Type == "error" {
+			return r, errors.New("xmpp: request failed")
+		}
+		return r, nil
+
+	case <-time.After(tweak_IQ_TIMEOUT * time.Second):
+		return nil, errors.New("xmpp: request timed out")
+
+	}
+
+}
+
+// moderate changes the role or affiliation of an occupant.
+func (c *Client) moderate(i item) error {
+	_, err := c.request(&iq{Type: "set", To: c.Room, Admin: &admin{Item: i}})
+	return err
+}
+
+// occupant returns the occupant using given nickname, or nil.
+func (c *Client) occupant(nick string) *item {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.occupants[nick]
+}
+
+// Public sends a message to the room.
+func (c *Client) Public(text string) {
+	c.send(&message{To: c.Room, Type: "groupchat", Body: text})
+}
+
+// Private sends a private message to an occupant of the room.
+func (c *Client) Private(nick, text string) {
+	c.send(&message{To: c.Room + "/" + nick, Type: "chat", Body: text})
+}
+
+// Kick removes an occupant from the room.
+func (c *Client) Kick(nick, reason string) {
+	c.moderate(item{Nick: nick, Role: "none", Reason: reason})
+}
+
+// Ban bans the account behind a nickname, this only works if the room tells
+// us who it is.
+func (c *Client) Ban(nick string) bool {
+
+	o := c.occupant(nick)
+	if o == nil || o.JID == "" {
+		return false
+	}
+
+	jid := bare(o.JID)
+
+	if c.moderate(item{JID: jid, Affiliation: "outcast"}) != nil {
+		return false
+	}
+
+	// Remember who this was, banned occupants leave the room.
+	c.mutex.Lock()
+	c.banned[nick] = jid
+	c.mutex.Unlock()
+
+	return true
+}
+
+// UnBan lifts a ban.
+func (c *Client) UnBan(nick string) {
+
+	c.mutex.Lock()
+	jid, known := c.banned[nick]
+	delete(c.banned, nick)
+	c.mutex.Unlock()
+
+	if known {
+		c.moderate(item{JID: jid, Affiliation: "none"})
+	}
+
+}
+
+// IsOperator returns true if the bot is a moderator that can ban people.
+func (c *Client) IsOperator() bool {
+
+	c.mutex.Lock()
+	o := c.occupants[c.Nick]
+	c.mutex.Unlock()
+
+	return o != nil && o.Role == "moderator" && (o.Affiliation == "admin" || o.Affiliation == "owner")
+}
+
+// IsAdmin returns true if given occupant is a moderator.
+func (c *Client) IsAdmin(nick string) bool {
+
+	o := c.occupant(nick)
+
+	return o != nil && o.Role == "moderator"
+}
+
+// Identity returns the real JID behind a nickname, if the room shares it.
+func (c *Client) Identity(nick string) string {
+
+	if o := c.occupant(nick); o != nil && o.JID != "" {
+		return bare(o.JID)
+	}
+
+	return ""
+}
+
+// nick returns the nickname of the bot, the room might change it.
+func (c *Client) nick() string {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.Nick
+}
+
+// bare strips the resource from a JID.
+func bare(jid string) string {
+	if i := strings.Index(jid, "/"); i >= 0 {
+		return jid[:i]
+	}
+	return jid
+}
+
+// resource returns the resource of a JID, the nickname for occupants.
+func resource(jid string) string {
+	if i := strings.Index(jid, "/"); i >= 0 {
+		return jid[i+1:]
+	}
+	return ""
+}
+
+// domain returns the domain of a JID.
+func domain(jid string) string {
+	jid = bare(jid)
+	if i := strings.Index(jid, "@"); i >= 0 {
+		return jid[i+1:]
+	}
+	return jid
+}
+
+// local returns the local part of a JID.
+func local(jid string) string {
+	if i := strings.Index(jid, "@"); i >= 0 {
+		return jid[:i]
+	}
+	return ""
+}
@@ -0,0 +1,316 @@
+package xmpp
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sorcix/passthebomb/ptb"
+)
+
+// element is a stanza received by the fake server.
+type element struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   string     `xml:",innerxml"`
+}
+
+// attr returns the value of given attribute.
+func (e *element) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// server is a fake XMPP server for a single client. It handles the stream,
+// authentication and resource binding, answers requests and passes all
+// stanzas it receives to the test.
+type server struct {
+	listener net.Listener
+	features string // Features offered before authentication.
+	stanzas  chan *element
+
+	write sync.Mutex
+	conn  net.Conn
+}
+
+func newServer(t *testing.T, features string) *server {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{listener: l, features: features, stanzas: make(chan *element, 100)}
+
+	go s.serve()
+
+	return s
+}
+
+// client returns a client connecting to the fake server.
+func (s *server) client() *Client {
+	c := NewClient("bot@example.org", "secret", "room@conference.example.org", "bomb")
+	c.Server = s.listener.Addr().String()
+	return c
+}
+
+// send writes raw XML to the client.
+func (s *server) send(x string) {
+
+	s.write.Lock()
+	defer s.write.Unlock()
+
+	if s.conn != nil {
+		io.WriteString(s.conn, x)
+	}
+
+}
+
+// close ends the stream.
+func (s *server) close() {
+	s.send("</stream:stream>")
+	s.listener.Close()
+}
+
+func (s *server) serve() {
+
+	defer close(s.stanzas)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+
+	s.write.Lock()
+	s.conn = conn
+	s.write.Unlock()
+
+	decoder := xml.NewDecoder(conn)
+	authenticated := false
+
+	for {
+
+		t, err := decoder.Token()
+		if err != nil {
+			return
+		}
+
+		start, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if start.Name.Local == "stream" {
+			s.send("<?xml version='1.0'?><stream:stream from='example.org' xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>")
+			if authenticated {
+				s.send("<stream:features><bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'/></stream:features>")
+			} else {
+				s.send("<stream:features>" + s.features + "</stream:features>")
+			}
+			continue
+		}
+
+		e := new(element)
+		if decoder.DecodeElement(e, &start) != nil {
+			return
+		}
+
+		s.stanzas <- e
+
+		switch {
+
+		case e.XMLName.Local == "auth":
+			s.send("<success xmlns='urn:ietf:params:xml:ns:xmpp-sasl'/>")
+			authenticated = true
+			// The client starts a new stream.
+			decoder = xml.NewDecoder(conn)
+
+		case e.XMLName.Local == "iq" && e.attr("id") == "bind":
+			s.send("<iq type='result' id='bind'><bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'><jid>bot@example.org/ptb</jid></bind></iq>")
+
+		case e.XMLName.Local == "iq" && e.attr("type") == "set":
+			s.send("<iq type='result' id='" + e.attr("id") + "' from='room@conference.example.org'/>")
+
+		}
+
+	}
+
+}
+
+// expect waits for a stanza with given name, containing given text.
+// Other stanzas are skipped.
+func (s *server) expect(t *testing.T, name, text string) *element {
+
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case e, ok := <-s.stanzas:
+			if !ok {
+				t.Fatalf("connection closed while waiting for %s with %q", name, text)
+			}
+			if e.XMLName.Local == name && strings.Contains(e.Inner, text) {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("no %s with %q", name, text)
+		}
+	}
+}
+
+// occupant returns the presence of an occupant of the room.
+func occupant(nick, jid, role, affiliation, codes string) string {
+	return "<presence from='room@conference.example.org/" + nick + "'><x xmlns='http://jabber.org/protocol/muc#user'>" +
+		"<item jid='" + jid + "' role='" + role + "' affiliation='" + affiliation + "'/>" + codes + "</x></presence>"
+}
+
+// groupchat returns a message in the room.
+func groupchat(nick, body string) string {
+	return "<message from='room@conference.example.org/" + nick + "' type='groupchat'><body>" + body + "</body></message>"
+}
+
+const plain = "<mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>PLAIN</mechanism></mechanisms>"
+
+func TestConnect(t *testing.T) {
+
+	s := newServer(t, plain)
+	defer s.close()
+
+	c := s.client()
+	c.Insecure = true
+
+	if err := c.connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+
+	a := s.expect(t, "auth", "")
+	if a.attr("mechanism") != "PLAIN" {
+		t.Fatalf("mechanism is %q", a.attr("mechanism"))
+	}
+	if b, _ := base64.StdEncoding.DecodeString(a.Inner); string(b) != "\x00bot\x00secret" {
+		t.Fatalf("credentials are %q", b)
+	}
+
+	s.expect(t, "iq", "<resource>ptb</resource>")
+
+	if c.JID != "bot@example.org/ptb" {
+		t.Fatalf("bound to %q", c.JID)
+	}
+
+}
+
+func TestInsecure(t *testing.T) {
+
+	s := newServer(t, plain)
+	defer s.close()
+
+	c := s.client()
+
+	if err := c.connect(); err == nil {
+		t.Fatal("logged in without TLS")
+	}
+	c.close()
+
+	// The password wasn't sent.
+	for e := range s.stanzas {
+		if e.XMLName.Local == "auth" {
+			t.Fatal("password sent without TLS")
+		}
+	}
+
+}
+
+func TestRun(t *testing.T) {
+
+	s := newServer(t, plain)
+
+	c := s.client()
+	c.Insecure = true
+
+	g := ptb.NewGame(c)
+	g.Configure("outbound_burst", "0")
+
+	done := make(chan error)
+	go func() {
+		done <- c.Run(g)
+	}()
+
+	// Entering the room.
+	if p := s.expect(t, "presence", "http://jabber.org/protocol/muc"); p.attr("to") != "room@conference.example.org/bomb" {
+		t.Fatalf("joined %q", p.attr("to"))
+	}
+
+	s.send(occupant("alice", "alice@example.org/phone", "moderator", "member", ""))
+	s.send(occupant("bob", "bob@example.org/pc", "participant", "none", ""))
+	s.send(occupant("ptb", "bot@example.org/ptb", "moderator", "owner", "<status code='110'/>"))
+
+	// History and our own messages are ignored.
+	s.send("<message from='room@conference.example.org/alice' type='groupchat'><body>!join</body><delay xmlns='urn:xmpp:delay' stamp='2020-01-01T00:00:00Z'/></message>")
+	s.send(groupchat("ptb", "!join"))
+
+	go g.Start()
+	s.expect(t, "message", "Attentiooooon")
+
+	// Messages in the room go to the game.
+	s.send(groupchat("alice", "!join"))
+	if m := s.expect(t, "message", "enlisted"); m.attr("to") != "room@conference.example.org/alice" || m.attr("type") != "chat" {
+		t.Fatalf("private message sent to %q as %q", m.attr("to"), m.attr("type"))
+	}
+
+	// Nickname changes are renames.
+	s.send("<presence from='room@conference.example.org/alice' type='unavailable'><x xmlns='http://jabber.org/protocol/muc#user'>" +
+		"<item nick='al' jid='alice@example.org/phone' role='moderator'/><status code='303'/></x></presence>")
+	s.send(occupant("al", "alice@example.org/phone", "moderator", "member", ""))
+	s.expect(t, "message", "alice is acting like a complete asshole and is now known as al")
+
+	// Leaving the room leaves the game.
+	s.send("<presence from='room@conference.example.org/al' type='unavailable'><x xmlns='http://jabber.org/protocol/muc#user'>" +
+		"<item role='none'/></x></presence>")
+	s.expect(t, "message", "al has gone AWOL")
+
+	// The room changed our nickname.
+	if c.nick() != "ptb" || !c.IsOperator() || c.IsAdmin("bob") {
+		t.Fatal("roles not tracked")
+	}
+
+	if c.Identity("bob") != "bob@example.org" {
+		t.Fatalf("bob is %q", c.Identity("bob"))
+	}
+
+	// Bans use the real JID, kicks the nickname.
+	if !c.Ban("bob") {
+		t.Fatal("ban failed")
+	}
+	s.expect(t, "iq", "jid=\"bob@example.org\" affiliation=\"outcast\"")
+
+	c.UnBan("bob")
+	s.expect(t, "iq", "jid=\"bob@example.org\" affiliation=\"none\"")
+
+	c.Kick("bob", "boom")
+	s.expect(t, "iq", "nick=\"bob\" role=\"none\"><reason>boom</reason>")
+
+	// Pings are answered.
+	s.send("<iq type='get' id='ping1' from='example.org'><ping xmlns='urn:xmpp:ping'/></iq>")
+	if r := s.expect(t, "iq", ""); r.attr("id") != "ping1" || r.attr("type") != "result" {
+		t.Fatalf("ping answered with %q", r.attr("type"))
+	}
+
+	s.close()
+
+	if err := <-done; err == nil {
+		t.Fatal("Run returned without error")
+	}
+
+}